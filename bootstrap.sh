@@ -4,4 +4,6 @@
 
 go get github.com/siddontang/go-log/log
 go get github.com/siddontang/go-snappy/snappy
-go get github.com/siddontang/copier
\ No newline at end of file
+go get github.com/siddontang/copier
+go get github.com/siddontang/rdb
+go get github.com/syndtr/goleveldb/leveldb
\ No newline at end of file
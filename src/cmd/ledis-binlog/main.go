@@ -56,23 +56,24 @@ func main() {
 	}
 
 	rb := bufio.NewReaderSize(f, 4096)
-	err = ledis.ReadEventFromReader(rb, printEvent)
+	err = ledis.ReadBinLogEvents(rb, printEvent)
 	if err != nil {
 		println("read event error: ", err.Error())
 		return
 	}
 }
 
-func printEvent(createTime uint32, event []byte) error {
-	if createTime < startTime || createTime > stopTime {
+func printEvent(e *ledis.BinLogEvent) error {
+	if e.CreateTime < startTime || e.CreateTime > stopTime {
 		return nil
 	}
 
-	t := time.Unix(int64(createTime), 0)
+	t := time.Unix(int64(e.CreateTime), 0)
 
-	fmt.Printf("%s ", t.Format(TimeFormat))
+	//event id is 0 in binlog format v1
+	fmt.Printf("%s %d ", t.Format(TimeFormat), e.ID)
 
-	s, err := ledis.FormatBinLogEvent(event)
+	s, err := ledis.FormatBinLogEvent(e.Payload)
 	if err != nil {
 		fmt.Printf("%s", err.Error())
 	} else {
@@ -79,9 +79,19 @@ var helpCommands = [][]string{
 	{"BTTL", "key", "Bitmap"},
 	{"BPERSIST", "key", "Bitmap"},
 	{"SLAVEOF", "host port", "Replication"},
-	{"FULLSYNC", "-", "Replication"},
+	{"FULLSYNC", "[key]", "Replication"},
 	{"SYNC", "index offset", "Replication"},
+	{"PSYNC", "index offset", "Replication"},
+	{"PURGE", "BINLOGS TO index | BINLOGS BEFORE timestamp", "Replication"},
+	{"WAIT", "numslaves timeout", "Replication"},
+	{"ROLE", "-", "Replication"},
 	{"PING", "-", "Server"},
 	{"ECHO", "message", "Server"},
 	{"SELECT", "index", "Server"},
+	{"CLIENT", "LIST | KILL addr|id | SETNAME name | GETNAME", "Server"},
+	{"SHUTDOWN", "-", "Server"},
+	{"CONFIG", "GET pattern | SET parameter value | REWRITE", "Server"},
+	{"COMPACT", "[index [type]]", "Server"},
+	{"INFO", "[section]", "Server"},
+	{"BACKUP", "path", "Server"},
 }
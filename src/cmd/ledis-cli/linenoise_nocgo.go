@@ -0,0 +1,41 @@
+// +build !cgo
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+//without cgo, linenoise can not be used,
+//read line from stdin simply, no history and completion supported.
+
+var stdin = bufio.NewReader(os.Stdin)
+
+func line(prompt string) (string, error) {
+	fmt.Print(prompt)
+
+	s, err := stdin.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(s, "\r\n"), nil
+}
+
+func addHistory(line string) error {
+	return nil
+}
+
+func setHistoryCapacity(capacity int) error {
+	return nil
+}
+
+// CompletionHandler provides possible completions for given input
+type CompletionHandler func(input string) []string
+
+// SetCompletionHandler sets the CompletionHandler to be used for completion
+func SetCompletionHandler(c CompletionHandler) {
+}
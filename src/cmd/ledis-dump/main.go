@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
+	"io"
+	"ledis"
 	"ledis/server"
 	"net"
 	"os"
@@ -13,6 +15,9 @@ var host = flag.String("host", "127.0.0.1", "ledis server host")
 var port = flag.Int("port", 6380, "ledis server port")
 var sock = flag.String("sock", "", "ledis unix socket domain")
 var dumpFile = flag.String("o", "./ledis.dump", "dump file to save")
+var dbs = flag.String("db", "", "only dump the dbs, like 0,1")
+var types = flag.String("type", "", "only dump the data types, like hash,zset")
+var pattern = flag.String("pattern", "", "only dump the keys matched, like user:*")
 
 var fullSyncCmd = []byte("*1\r\n$8\r\nfullsync\r\n") //fullsync
 
@@ -23,6 +28,12 @@ func main() {
 	var err error
 	var f *os.File
 
+	filter, err := ledis.NewDumpFilter(*dbs, *types, *pattern)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+
 	if f, err = os.OpenFile(*dumpFile, os.O_CREATE|os.O_WRONLY, os.ModePerm); err != nil {
 		println(err.Error())
 		return
@@ -53,7 +64,20 @@ func main() {
 
 	rb := bufio.NewReaderSize(c, 16*1024)
 
-	if err = server.ReadBulkTo(rb, f); err != nil {
+	var r *server.FullSyncReader
+	if r, err = server.NewFullSyncReader(rb); err != nil {
+		println(err.Error())
+		return
+	}
+
+	if filter == nil {
+		_, err = io.Copy(f, r)
+	} else {
+		//the full dump from server is filtered on the fly
+		_, err = ledis.FilterDump(r, f, filter)
+	}
+
+	if err != nil {
 		println(err.Error())
 		return
 	}
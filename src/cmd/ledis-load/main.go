@@ -6,10 +6,15 @@ import (
 	"fmt"
 	"ledis"
 	"io/ioutil"
+	"os"
 )
 
 var configPath = flag.String("config", "/etc/ledis.json", "ledisdb config file")
 var dumpPath = flag.String("dump_file", "", "ledisdb dump file")
+var dbs = flag.String("db", "", "only load the dbs, like 0,1")
+var types = flag.String("type", "", "only load the data types, like hash,zset")
+var pattern = flag.String("pattern", "", "only load the keys matched, like user:*")
+var checkpoint = flag.Bool("binlog_checkpoint", false, "do not log loaded keys to binlog but purge all binlogs, slaves must do a fullsync later")
 
 func main() {
 	flag.Parse()
@@ -36,6 +41,12 @@ func main() {
 		return
 	}
 
+	filter, err := ledis.NewDumpFilter(*dbs, *types, *pattern)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+
 	if len(cfg.DataDir) == 0 {
 		println("must set data dir")
 		return
@@ -47,7 +58,7 @@ func main() {
 		return
 	}
 
-	err = loadDump(&cfg, ldb)
+	err = loadDump(&cfg, ldb, filter)
 	ldb.Close()
 
 	if err != nil {
@@ -58,14 +69,33 @@ func main() {
 	println("Load OK")
 }
 
-func loadDump(cfg *ledis.Config, ldb *ledis.Ledis) error {
-	var err error
-	if err = ldb.FlushAll(); err != nil {
+//without filter, all data are replaced by the dump,
+//otherwise the selected keys replace the existing ones and the other data are kept.
+func loadDump(cfg *ledis.Config, ldb *ledis.Ledis, filter *ledis.DumpFilter) error {
+	f, err := os.Open(*dumpPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	//verify the whole dump first, the data is not changed if it is corrupted
+	if _, err = ledis.VerifyDump(f); err != nil {
 		return err
+	} else if _, err = f.Seek(0, os.SEEK_SET); err != nil {
+		return err
+	}
+
+	if filter == nil {
+		if err = ldb.FlushAll(); err != nil {
+			return err
+		}
 	}
 
 	var head *ledis.MasterInfo
-	head, err = ldb.LoadDumpFile(*dumpPath)
+	head, err = ldb.LoadDumpWithOptions(f, &ledis.LoadDumpOptions{
+		Filter:           filter,
+		BinLogCheckpoint: *checkpoint,
+	})
 
 	if err != nil {
 		return err
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"ledis"
+	"os"
+)
+
+var configPath = flag.String("config", "/etc/ledis.json", "ledisdb config file")
+var importPath = flag.String("import", "", "redis rdb file to import")
+var exportPath = flag.String("export", "", "redis rdb file to export to")
+
+func main() {
+	flag.Parse()
+
+	if len(*configPath) == 0 {
+		println("need ledis config file")
+		return
+	}
+
+	data, err := ioutil.ReadFile(*configPath)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+
+	if len(*importPath) == 0 && len(*exportPath) == 0 {
+		println("need import or export rdb file")
+		return
+	} else if len(*importPath) > 0 && len(*exportPath) > 0 {
+		println("can not import and export at the same time")
+		return
+	}
+
+	var cfg ledis.Config
+	if err = json.Unmarshal(data, &cfg); err != nil {
+		println(err.Error())
+		return
+	}
+
+	if len(cfg.DataDir) == 0 {
+		println("must set data dir")
+		return
+	}
+
+	ldb, err := ledis.Open(&cfg)
+	if err != nil {
+		println("ledis open error ", err.Error())
+		return
+	}
+
+	var n int64
+	if len(*importPath) > 0 {
+		n, err = importRDB(ldb)
+	} else {
+		n, err = exportRDB(ldb)
+	}
+
+	ldb.Close()
+
+	if err != nil {
+		println(err.Error())
+		return
+	}
+
+	fmt.Printf("%d keys OK\n", n)
+}
+
+func importRDB(ldb *ledis.Ledis) (int64, error) {
+	f, err := os.Open(*importPath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return ldb.ImportRDB(f)
+}
+
+func exportRDB(ldb *ledis.Ledis) (int64, error) {
+	f, err := os.Create(*exportPath)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := ldb.ExportRDB(f)
+	if err != nil {
+		f.Close()
+		return n, err
+	}
+
+	return n, f.Close()
+}
@@ -3,9 +3,9 @@ package main
 import (
 	"encoding/json"
 	"flag"
-	"ledis"
-	"leveldb"
 	"io/ioutil"
+	"ledis"
+	"store"
 )
 
 var fileName = flag.String("config", "/etc/ledis.json", "ledisdb config file")
@@ -35,7 +35,7 @@ func main() {
 		return
 	}
 
-	if err = leveldb.Repair(cfg.NewDBConfig()); err != nil {
+	if err = store.Repair(cfg.NewDBConfig()); err != nil {
 		println("repair error: ", err.Error())
 	}
 }
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"ledis"
+	"ledis/server"
+	"os"
+	"path"
+)
+
+var configPath = flag.String("config", "/etc/ledis.json", "ledisdb config file")
+var backupPath = flag.String("backup_dir", "", "backup dir made by BACKUP command")
+var masterAddr = flag.String("master", "", "master addr, if set, the restored server can sync from the backup binlog position as a slave")
+
+func main() {
+	flag.Parse()
+
+	if len(*configPath) == 0 {
+		println("need ledis config file")
+		return
+	}
+
+	data, err := ioutil.ReadFile(*configPath)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+
+	if len(*backupPath) == 0 {
+		println("need backup dir")
+		return
+	}
+
+	var cfg server.Config
+	if err = json.Unmarshal(data, &cfg); err != nil {
+		println(err.Error())
+		return
+	}
+
+	if len(cfg.DataDir) == 0 {
+		println("must set data dir")
+		return
+	}
+
+	if err = restore(&cfg); err != nil {
+		println(err.Error())
+		return
+	}
+
+	println("Restore OK")
+}
+
+func restore(cfg *server.Config) error {
+	info := new(ledis.BackupInfo)
+	if err := info.Load(path.Join(*backupPath, ledis.BackupInfoName)); err != nil {
+		return err
+	}
+
+	if len(cfg.DB.Name) > 0 && cfg.DB.Name != info.Store {
+		return fmt.Errorf("backup store is %s, but config store is %s", info.Store, cfg.DB.Name)
+	}
+
+	dataDir := path.Join(cfg.DataDir, ledis.BackupDataName)
+	if _, err := os.Stat(dataDir); err == nil {
+		return fmt.Errorf("%s already exists, remove it first", dataDir)
+	}
+
+	if err := copyDir(path.Join(*backupPath, ledis.BackupDataName), dataDir); err != nil {
+		os.RemoveAll(dataDir)
+		return err
+	}
+
+	if len(*masterAddr) > 0 {
+		m := &server.MasterInfo{
+			Addr:         *masterAddr,
+			LogFileIndex: info.LogFileIndex,
+			LogPos:       info.LogPos,
+		}
+
+		if err := m.Save(path.Join(cfg.DataDir, "master.info")); err != nil {
+			return err
+		}
+	}
+
+	//master enable binlog, here output this like mysql
+	if info.LogFileIndex != 0 && info.LogPos != 0 {
+		format := "MASTER_LOG_FILE='binlog.%07d', MASTER_LOG_POS=%d;\n"
+		fmt.Printf(format, info.LogFileIndex, info.LogPos)
+	}
+
+	return nil
+}
+
+func copyDir(src string, dst string) error {
+	fs, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(dst, os.ModePerm); err != nil {
+		return err
+	}
+
+	for _, f := range fs {
+		s := path.Join(src, f.Name())
+		d := path.Join(dst, f.Name())
+
+		if f.IsDir() {
+			err = copyDir(s, d)
+		} else {
+			err = copyFile(s, d)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyFile(src string, dst string) error {
+	sf, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer sf.Close()
+
+	df, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(df, sf); err != nil {
+		df.Close()
+		return err
+	}
+
+	if err = df.Sync(); err != nil {
+		df.Close()
+		return err
+	}
+
+	return df.Close()
+}
@@ -42,8 +42,10 @@ func main() {
 		syscall.SIGQUIT)
 
 	go func() {
-		<-sc
+		s := <-sc
 
+		//stop accepting, wait running commands to finish, then flush and close the database
+		println("receive signal", s.String(), ", shutdown now")
 		app.Close()
 	}()
 
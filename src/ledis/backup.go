@@ -0,0 +1,131 @@
+package ledis
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"store"
+	"time"
+)
+
+//backup directory layout
+// data          the store data, can be used as data_dir/data directly
+// backup.info   the binlog position of the backup in json
+//
+//the backup is made from a snapshot, so writes can go on during the backup
+
+const (
+	BackupDataName = "data"
+	BackupInfoName = "backup.info"
+)
+
+//number of keys committed in one write batch when backup
+const backupBatchNum = 1024
+
+type BackupInfo struct {
+	LogFileIndex int64  `json:"log_file_index"`
+	LogPos       int64  `json:"log_pos"`
+	Store        string `json:"store"`
+	CreateTime   int64  `json:"create_time"`
+}
+
+func (b *BackupInfo) Save(filePath string) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filePath, data, 0644)
+}
+
+func (b *BackupInfo) Load(filePath string) error {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, b)
+}
+
+//Backup copies a consistent view of all data into dir, dir must not exist or be empty.
+//The memory store is backed up with the default store driver, because it has no files.
+func (l *Ledis) Backup(dir string) (*BackupInfo, error) {
+	if fs, err := ioutil.ReadDir(dir); err == nil && len(fs) > 0 {
+		return nil, fmt.Errorf("backup dir %s is not empty", dir)
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	info, err := l.backup(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	return info, nil
+}
+
+func (l *Ledis) backup(dir string) (*BackupInfo, error) {
+	sp, m, err := l.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer sp.Close()
+
+	cfg := *l.ldb.Config()
+	cfg.Path = path.Join(dir, BackupDataName)
+	if cfg.Name == "memory" {
+		cfg.Name = store.DefaultDriverName
+	}
+
+	db, err := store.Open(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	wb := db.NewWriteBatch()
+	defer wb.Close()
+
+	it := sp.NewIterator()
+	defer it.Close()
+
+	n := 0
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		wb.Put(it.Key(), it.Value())
+
+		if n++; n%backupBatchNum == 0 {
+			if err = wb.Commit(); err != nil {
+				return nil, err
+			}
+			wb.Rollback()
+		}
+	}
+
+	if err = wb.SyncCommit(); err != nil {
+		return nil, err
+	}
+
+	info := &BackupInfo{
+		LogFileIndex: m.LogFileIndex,
+		LogPos:       m.LogPos,
+		Store:        cfg.Name,
+		CreateTime:   time.Now().Unix(),
+	}
+
+	if len(info.Store) == 0 {
+		info.Store = store.DefaultDriverName
+	}
+
+	if err = info.Save(path.Join(dir, BackupInfoName)); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
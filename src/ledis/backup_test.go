@@ -0,0 +1,161 @@
+package ledis
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"testing"
+)
+
+func TestBackup(t *testing.T) {
+	os.RemoveAll("/tmp/test_ledis_backup")
+	os.RemoveAll("/tmp/test_ledis_backup_dir")
+
+	var cfgJson = []byte(`
+    {
+        "data_dir" : "/tmp/test_ledis_backup",
+        "binlog" : {
+            "use" : true
+        }
+    }
+    `)
+
+	l, err := OpenWithJsonConfig(cfgJson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	db, _ := l.Select(1)
+	db.Set([]byte("a"), []byte("1"))
+	db.HSet([]byte("b"), []byte("f"), []byte("2"))
+
+	//the writers run during the backup, every write has a unique key
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for n := 0; ; n++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				if err := db.Set([]byte(fmt.Sprintf("w%d_%d", i, n)), []byte("v")); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+
+	info, err := l.Backup("/tmp/test_ledis_backup_dir")
+
+	//writes after the snapshot are not in the backup
+	db.Set([]byte("c"), []byte("3"))
+
+	close(stop)
+	wg.Wait()
+
+	if err != nil {
+		t.Fatal(err)
+	} else if info.LogFileIndex == 0 || info.LogPos == 0 {
+		t.Fatal("invalid binlog position", info.LogFileIndex, info.LogPos)
+	}
+
+	if _, err := l.Backup("/tmp/test_ledis_backup_dir"); err == nil {
+		t.Fatal("must error for not empty dir")
+	}
+
+	loaded := new(BackupInfo)
+	if err := loaded.Load(path.Join("/tmp/test_ledis_backup_dir", BackupInfoName)); err != nil {
+		t.Fatal(err)
+	} else if *loaded != *info {
+		t.Fatal(loaded, info)
+	}
+
+	//the backup dir can be opened as data_dir directly
+	var backupJson = []byte(`
+    {
+        "data_dir" : "/tmp/test_ledis_backup_dir"
+    }
+    `)
+
+	b, err := OpenWithJsonConfig(backupJson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	bdb, _ := b.Select(1)
+	if v, err := bdb.Get([]byte("a")); err != nil {
+		t.Fatal(err)
+	} else if string(v) != "1" {
+		t.Fatal(string(v))
+	}
+
+	if v, err := bdb.HGet([]byte("b"), []byte("f")); err != nil {
+		t.Fatal(err)
+	} else if string(v) != "2" {
+		t.Fatal(string(v))
+	}
+
+	if v, err := bdb.Get([]byte("c")); err != nil {
+		t.Fatal(err)
+	} else if v != nil {
+		t.Fatal("must nil")
+	}
+
+	//the backup is exactly the data at its binlog position:
+	//no write after the position is in the backup, and the backup with the later binlog is the same as the master
+	pos := &MasterInfo{info.LogFileIndex, info.LogPos}
+	replayed := 0
+
+	var buf bytes.Buffer
+	for {
+		buf.Reset()
+		n, err := l.ReadEventsTo(pos, &buf)
+		if err != nil {
+			t.Fatal(err)
+		} else if pos.LogFileIndex <= 0 {
+			t.Fatal("binlog position lost", pos.LogFileIndex)
+		}
+
+		data := buf.Bytes()
+		err = ReadEventFromReader(bytes.NewReader(data), func(createTime uint32, event []byte) error {
+			replayed++
+
+			if event[0] != BinLogTypePut {
+				return nil
+			}
+
+			key, _, err := decodeBinLogPut(event)
+			if err != nil {
+				return err
+			} else if v, _ := b.ldb.Get(key); v != nil {
+				return fmt.Errorf("write %q after backup position is in backup", key)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if err = b.ReplicateFromData(data); err != nil {
+			t.Fatal(err)
+		}
+
+		if n == 0 {
+			break
+		}
+	}
+
+	if replayed == 0 {
+		t.Fatal("no write after backup")
+	} else if err = checkLedisEqual(l, b); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,15 +2,18 @@ package ledis
 
 import (
 	"bufio"
-	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/siddontang/go-log/log"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -28,19 +31,67 @@ src.src.ledis-bin.00001
 src.src.ledis-bin.00002
 src.src.ledis-bin.00003
 
-log file format
+log file format v2
+
+header: magic "LEDISBIN"|version(bigendian uint32)|first event id(bigendian uint64)
+events: timestamp(bigendian uint32, seconds)|event id(bigendian uint64)|PayloadLen(bigendian uint32)|PayloadData|crc32(bigendian uint32)
+
+event id increases monotonically in all log files, crc32 is IEEE of the event without crc32.
+
+log file format v1, without header, can still be read
 
 timestamp(bigendian uint32, seconds)|PayloadLen(bigendian uint32)|PayloadData
 
 */
 
+const (
+	binLogMagic = "LEDISBIN"
+
+	BinLogVersion uint32 = 2
+
+	binLogHeaderSize = 8 + 4 + 8
+)
+
+const (
+	FsyncAlways   = "always"
+	FsyncEverySec = "everysec"
+	FsyncNo       = "no"
+)
+
+func checkFsync(policy string) error {
+	switch policy {
+	case FsyncAlways, FsyncEverySec, FsyncNo:
+		return nil
+	default:
+		return fmt.Errorf("invalid binlog fsync %s, must be always, everysec or no", policy)
+	}
+}
+
+var (
+	ErrBinLogInUse      = errors.New("binlog is in use by slaves")
+	ErrBinLogNotEnabled = errors.New("binlog is not enabled")
+)
+
 type BinLogConfig struct {
 	Path        string `json:"path"`
 	MaxFileSize int    `json:"max_file_size"`
 	MaxFileNum  int    `json:"max_file_num"`
+
+	//purge the log files last modified more than n seconds ago, 0 means no limit
+	MaxAge int `json:"max_age"`
+
+	//purge the oldest log files when the total size exceeds n bytes, 0 means no limit
+	MaxTotalSize int `json:"max_total_size"`
+
+	//when to fsync log file, always (every commit), everysec (in background) or no (by OS), default everysec
+	Fsync string `json:"fsync"`
 }
 
 func (cfg *BinLogConfig) adjust() {
+	if len(cfg.Fsync) == 0 {
+		cfg.Fsync = FsyncEverySec
+	}
+
 	if cfg.MaxFileSize <= 0 {
 		cfg.MaxFileSize = DefaultBinLogFileSize
 	} else if cfg.MaxFileSize > MaxBinLogFileSize {
@@ -64,6 +115,32 @@ type BinLog struct {
 	indexName    string
 	logNames     []string
 	lastLogIndex int64
+
+	lastEventID uint64
+
+	//the position and last event id before the last Log, to undo it
+	undoPos     int64
+	undoEventID uint64
+
+	//returns the smallest log file index still in use, 0 if none
+	inUse func() int64
+
+	//guards changing logFile against the background fsync
+	syncLock sync.Mutex
+
+	//1 if some logs are not synced in everysec mode
+	dirty int32
+
+	statsLock sync.Mutex
+	stats     FsyncStats
+}
+
+//FsyncStats is the latency of log file fsyncs
+type FsyncStats struct {
+	Count int64
+	Total time.Duration
+	Max   time.Duration
+	Last  time.Duration
 }
 
 func NewBinLogWithJsonConfig(data json.RawMessage) (*BinLog, error) {
@@ -79,6 +156,10 @@ func NewBinLogWithJsonConfig(data json.RawMessage) (*BinLog, error) {
 func NewBinLog(cfg *BinLogConfig) (*BinLog, error) {
 	cfg.adjust()
 
+	if err := checkFsync(cfg.Fsync); err != nil {
+		return nil, err
+	}
+
 	l := new(BinLog)
 
 	l.cfg = cfg
@@ -154,13 +235,17 @@ func (l *BinLog) loadIndex() error {
 		}
 	}
 
+	if err := l.recoverLastLogFile(); err != nil {
+		return err
+	}
+
 	var err error
 	if len(l.logNames) == 0 {
 		l.lastLogIndex = 1
 	} else {
 		lastName := l.logNames[len(l.logNames)-1]
 
-		if l.lastLogIndex, err = strconv.ParseInt(path.Ext(lastName)[1:], 10, 64); err != nil {
+		if l.lastLogIndex, err = parseLogFileIndex(lastName); err != nil {
 			log.Error("invalid logfile name %s", err.Error())
 			return err
 		}
@@ -172,6 +257,76 @@ func (l *BinLog) loadIndex() error {
 	return nil
 }
 
+//recoverLastLogFile truncates the partial or corrupted events at the tail of last log file,
+//which may be written when crashed, and loads the last event id.
+func (l *BinLog) recoverLastLogFile() error {
+	//the last log file may have no event, find the last event id in former files
+	for i := len(l.logNames) - 1; i >= 0; i-- {
+		found, err := l.recoverLogFile(l.logNames[i], i == len(l.logNames)-1)
+		if err != nil || found {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//recoverLogFile loads the last event id in the log file and truncates the broken tail if needed,
+//returns false if no event id in the file.
+func (l *BinLog) recoverLogFile(name string, truncate bool) (bool, error) {
+	logPath := path.Join(l.cfg.Path, name)
+
+	flag := os.O_RDONLY
+	if truncate {
+		flag = os.O_RDWR
+	}
+
+	f, err := os.OpenFile(logPath, flag, 0666)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	rb := bufio.NewReaderSize(f, 4096)
+
+	h, err := readBinLogHeader(rb)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		//empty, or header not written completely
+		if truncate {
+			return false, f.Truncate(0)
+		}
+		return false, nil
+	} else if err != nil {
+		return false, err
+	} else if h.Version != BinLogVersion {
+		//no event id in v1
+		return true, nil
+	}
+
+	l.lastEventID = h.FirstEventID - 1
+
+	pos := h.Size()
+	r := newBinLogEventReader(rb, h.Version)
+	for {
+		e, err := r.Read()
+		if err == io.EOF {
+			return true, nil
+		} else if err != nil {
+			if !truncate {
+				return false, err
+			}
+
+			log.Error("binlog %s has a broken event at %d, truncate it: %s", logPath, pos, err.Error())
+			return true, f.Truncate(pos)
+		}
+
+		pos += r.EventSize()
+		l.lastEventID = e.ID
+	}
+}
+
 func (l *BinLog) getLogFile() string {
 	return l.FormatLogFileName(l.lastLogIndex)
 }
@@ -181,23 +336,39 @@ func (l *BinLog) openNewLogFile() error {
 	lastName := l.getLogFile()
 
 	logPath := path.Join(l.cfg.Path, lastName)
-	if l.logFile, err = os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY, 0666); err != nil {
+	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
 		log.Error("open new logfile error %s", err.Error())
 		return err
 	}
 
+	l.syncLock.Lock()
+	l.logFile = f
+	l.syncLock.Unlock()
+
 	if l.cfg.MaxFileNum > 0 && len(l.logNames) == l.cfg.MaxFileNum {
-		l.purge(1)
+		l.purgeUnused(1)
 	}
 
 	l.logNames = append(l.logNames, lastName)
 
+	l.purgeRetention()
+
 	if l.logWb == nil {
 		l.logWb = bufio.NewWriterSize(l.logFile, 1024)
 	} else {
 		l.logWb.Reset(l.logFile)
 	}
 
+	h := &BinLogHeader{Version: BinLogVersion, FirstEventID: l.lastEventID + 1}
+	if err = h.Encode(l.logWb); err != nil {
+		return err
+	}
+
+	if err = l.logWb.Flush(); err != nil {
+		return err
+	}
+
 	if err = l.flushIndex(); err != nil {
 		return err
 	}
@@ -214,8 +385,7 @@ func (l *BinLog) checkLogFileSize() bool {
 	if st.Size() >= int64(l.cfg.MaxFileSize) {
 		l.lastLogIndex++
 
-		l.logFile.Close()
-		l.logFile = nil
+		l.closeLogFile(false)
 		return true
 	}
 
@@ -234,9 +404,84 @@ func (l *BinLog) purge(n int) {
 
 func (l *BinLog) Close() {
 	if l.logFile != nil {
-		l.logFile.Close()
-		l.logFile = nil
+		if err := l.logWb.Flush(); err != nil {
+			log.Error("flush binlog error %s", err.Error())
+		}
+
+		l.closeLogFile(true)
+	}
+}
+
+//closeLogFile closes current log file, it is synced if sync or not synced in everysec mode.
+func (l *BinLog) closeLogFile(sync bool) {
+	l.syncLock.Lock()
+	defer l.syncLock.Unlock()
+
+	if atomic.SwapInt32(&l.dirty, 0) == 1 || sync {
+		if err := l.fsync(l.logFile); err != nil {
+			log.Error("sync binlog error %s", err.Error())
+		}
+	}
+
+	l.logFile.Close()
+	l.logFile = nil
+}
+
+func (l *BinLog) fsync(f *os.File) error {
+	start := time.Now()
+	err := f.Sync()
+	d := time.Now().Sub(start)
+
+	l.statsLock.Lock()
+	l.stats.Count++
+	l.stats.Total += d
+	l.stats.Last = d
+	if d > l.stats.Max {
+		l.stats.Max = d
+	}
+	l.statsLock.Unlock()
+
+	return err
+}
+
+//SyncDirty syncs current log file if some logs are not synced in everysec mode,
+//it can be called without the lock of Log.
+func (l *BinLog) SyncDirty() error {
+	if atomic.SwapInt32(&l.dirty, 0) == 0 {
+		return nil
+	}
+
+	l.syncLock.Lock()
+	defer l.syncLock.Unlock()
+
+	//the closed log file has been synced
+	if l.logFile == nil {
+		return nil
 	}
+
+	return l.fsync(l.logFile)
+}
+
+//FsyncStats returns the latency of log file fsyncs since opened.
+func (l *BinLog) FsyncStats() FsyncStats {
+	l.statsLock.Lock()
+	defer l.statsLock.Unlock()
+	return l.stats
+}
+
+//SetFsync changes the fsync policy, always, everysec or no.
+func (l *BinLog) SetFsync(policy string) error {
+	if err := checkFsync(policy); err != nil {
+		return err
+	}
+
+	l.cfg.Fsync = policy
+	return nil
+}
+
+//Fsync returns the fsync policy.
+func (l *BinLog) Fsync() string {
+	return l.cfg.Fsync
 }
 
 func (l *BinLog) LogNames() []string {
@@ -256,10 +501,47 @@ func (l *BinLog) LogFilePos() int64 {
 	}
 }
 
+//LastEventID returns the id of the last logged event, 0 if no event.
+func (l *BinLog) LastEventID() uint64 {
+	return l.lastEventID
+}
+
 func (l *BinLog) LogFileIndex() int64 {
 	return l.lastLogIndex
 }
 
+//BytesAfter returns the approximate bytes logged after the position, the file headers are counted too.
+func (l *BinLog) BytesAfter(index int64, pos int64) int64 {
+	if index > l.lastLogIndex {
+		return 0
+	} else if index == l.lastLogIndex {
+		if n := l.LogFilePos() - pos; n > 0 {
+			return n
+		}
+		return 0
+	}
+
+	n := l.LogFilePos() - pos
+	for i := index; i < l.lastLogIndex; i++ {
+		if st, err := os.Stat(l.FormatLogFilePath(i)); err == nil {
+			n += st.Size()
+		}
+	}
+
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+func parseLogFileIndex(name string) (int64, error) {
+	ext := path.Ext(name)
+	if len(ext) == 0 {
+		return 0, fmt.Errorf("invalid logfile name %s", name)
+	}
+	return strconv.ParseInt(ext[1:], 10, 64)
+}
+
 func (l *BinLog) FormatLogFileName(index int64) string {
 	return fmt.Sprintf("ledis-bin.%07d", index)
 }
@@ -285,45 +567,303 @@ func (l *BinLog) Purge(n int) error {
 		}
 	}
 
-	l.purge(n)
+	l.purgeUnused(n)
 
 	return l.flushIndex()
 }
 
+//SetInUseFunc sets the function returning the smallest log file index still in use,
+//like by the connected slaves, the log files from it are not purged.
+func (l *BinLog) SetInUseFunc(f func() int64) {
+	l.inUse = f
+}
+
+//SetRetention changes max age in seconds and max total size in bytes of the log files,
+//the files exceeding them are purged when a new log file is created.
+func (l *BinLog) SetRetention(maxAge int, maxTotalSize int) {
+	l.cfg.MaxAge = maxAge
+	l.cfg.MaxTotalSize = maxTotalSize
+}
+
+//keepIndex returns the smallest log file index can not be purged
+func (l *BinLog) keepIndex() (int64, bool) {
+	if l.inUse != nil {
+		if index := l.inUse(); index > 0 && index < l.lastLogIndex {
+			return index, true
+		}
+	}
+	return l.lastLogIndex, false
+}
+
+//purgeable returns how many oldest log files in the first n can be purged,
+//returns ErrBinLogInUse if any is in use.
+func (l *BinLog) purgeable(n int) (int, error) {
+	keep, inUse := l.keepIndex()
+	for i := 0; i < n; i++ {
+		index, err := parseLogFileIndex(l.logNames[i])
+		if err != nil {
+			return 0, err
+		}
+
+		if index >= keep {
+			if inUse {
+				return i, ErrBinLogInUse
+			}
+			return i, nil
+		}
+	}
+	return n, nil
+}
+
+//PurgeTo purges the log files before the index, returns the number of purged files.
+func (l *BinLog) PurgeTo(index int64) (int, error) {
+	n := 0
+	for ; n < len(l.logNames); n++ {
+		if i, err := parseLogFileIndex(l.logNames[n]); err != nil {
+			return 0, err
+		} else if i >= index {
+			break
+		}
+	}
+
+	return l.purgeChecked(n)
+}
+
+//PurgeBefore purges the log files last modified before t, returns the number of purged files.
+func (l *BinLog) PurgeBefore(t time.Time) (int, error) {
+	n := 0
+	for ; n < len(l.logNames); n++ {
+		if st, err := os.Stat(path.Join(l.cfg.Path, l.logNames[n])); err == nil && !st.ModTime().Before(t) {
+			break
+		}
+	}
+
+	return l.purgeChecked(n)
+}
+
+func (l *BinLog) purgeChecked(n int) (int, error) {
+	n, err := l.purgeable(n)
+	if err != nil {
+		return 0, err
+	} else if n == 0 {
+		return 0, nil
+	}
+
+	l.purge(n)
+
+	return n, l.flushIndex()
+}
+
+//purgeRetention purges the oldest log files exceeding max age or max total size,
+//the files in use are kept.
+func (l *BinLog) purgeRetention() {
+	if l.cfg.MaxAge <= 0 && l.cfg.MaxTotalSize <= 0 {
+		return
+	}
+
+	sizes := make([]int64, len(l.logNames))
+	times := make([]time.Time, len(l.logNames))
+	var total int64
+	for i, name := range l.logNames {
+		if st, err := os.Stat(path.Join(l.cfg.Path, name)); err == nil {
+			sizes[i] = st.Size()
+			times[i] = st.ModTime()
+			total += sizes[i]
+		}
+	}
+
+	deadline := time.Now().Add(-time.Duration(l.cfg.MaxAge) * time.Second)
+
+	n := 0
+	for ; n < len(l.logNames); n++ {
+		expired := l.cfg.MaxAge > 0 && times[n].Before(deadline)
+		oversize := l.cfg.MaxTotalSize > 0 && total > int64(l.cfg.MaxTotalSize)
+		if !expired && !oversize {
+			break
+		}
+		total -= sizes[n]
+	}
+
+	l.purgeUnused(n)
+}
+
+//purgeUnused purges the oldest n log files but keeps the ones in use,
+//so the log files may exceed the limits until the slaves catch up.
+func (l *BinLog) purgeUnused(n int) {
+	m, err := l.purgeable(n)
+	if err == ErrBinLogInUse {
+		log.Warn("binlog exceeds limit, but %d files are in use", n-m)
+	} else if err != nil {
+		log.Error("check binlog purge error %s", err.Error())
+		return
+	}
+
+	l.purge(m)
+}
+
+// SetLimit changes max file size and max file num, the oldest logs exceed max file num are purged.
+func (l *BinLog) SetLimit(maxFileSize int, maxFileNum int) error {
+	l.cfg.MaxFileSize = maxFileSize
+	l.cfg.MaxFileNum = maxFileNum
+	l.cfg.adjust()
+
+	if len(l.logNames) > l.cfg.MaxFileNum {
+		return l.Purge(len(l.logNames) - l.cfg.MaxFileNum)
+	}
+
+	return nil
+}
+
+//Checkpoint purges all log files, and the later logs are written to a new log file,
+//so the slaves lose their binlog positions and have to do a fullsync.
+func (l *BinLog) Checkpoint() error {
+	if l.logFile != nil {
+		if err := l.logWb.Flush(); err != nil {
+			log.Error("flush binlog error %s", err.Error())
+			return err
+		}
+
+		l.closeLogFile(false)
+	}
+
+	//always use a new index, a slave may wait at the beginning of current index
+	l.lastLogIndex++
+
+	//the slaves in use can not resume from the dropped logs either, so the check is skipped here
+	l.purge(len(l.logNames))
+
+	//keep a log file with the last event id
+	return l.openNewLogFile()
+}
+
 func (l *BinLog) Log(args ...[]byte) error {
 	var err error
 
+	//check the size before logging, so the last Log can be undone in current log file
+	l.checkLogFileSize()
+
 	if l.logFile == nil {
 		if err = l.openNewLogFile(); err != nil {
 			return err
 		}
 	}
 
+	l.undoPos = l.LogFilePos()
+	l.undoEventID = l.lastEventID
+
 	//we treat log many args as a batch, so use same createTime
 	createTime := uint32(time.Now().Unix())
 
 	for _, data := range args {
-		payLoadLen := uint32(len(data))
+		l.lastEventID++
 
-		if err := binary.Write(l.logWb, binary.BigEndian, createTime); err != nil {
-			return err
+		if err = writeBinLogEvent(l.logWb, createTime, l.lastEventID, data); err != nil {
+			break
 		}
+	}
 
-		if err := binary.Write(l.logWb, binary.BigEndian, payLoadLen); err != nil {
-			return err
-		}
+	if err == nil {
+		err = l.logWb.Flush()
+	}
 
-		if _, err := l.logWb.Write(data); err != nil {
-			return err
+	if err == nil {
+		switch l.cfg.Fsync {
+		case FsyncAlways:
+			err = l.fsync(l.logFile)
+		case FsyncEverySec:
+			atomic.StoreInt32(&l.dirty, 1)
 		}
 	}
 
-	if err = l.logWb.Flush(); err != nil {
+	if err != nil {
 		log.Error("write log error %s", err.Error())
+
+		//remove the partial events
+		l.undoLast()
 		return err
 	}
 
-	l.checkLogFileSize()
+	return nil
+}
+
+//undoLast removes the events written by the last Log,
+//used when the data of the events fails to commit.
+func (l *BinLog) undoLast() error {
+	if l.logFile == nil {
+		return nil
+	}
+
+	//drop the events not flushed
+	l.logWb.Reset(l.logFile)
+
+	l.lastEventID = l.undoEventID
+
+	if err := l.logFile.Truncate(l.undoPos); err != nil {
+		log.Error("undo binlog error %s", err.Error())
+		return err
+	}
+
+	_, err := l.logFile.Seek(l.undoPos, os.SEEK_SET)
+	return err
+}
+
+//setLastEventID makes the next event id after id, only used before any Log after opened.
+func (l *BinLog) setLastEventID(id uint64) {
+	l.lastEventID = id
+}
+
+//readEventsAfter reads the events with greater id than id in log files.
+func (l *BinLog) readEventsAfter(id uint64, f func(e *BinLogEvent) error) error {
+	//find the log file with the event after id
+	start := len(l.logNames)
+	for i := len(l.logNames) - 1; i >= 0; i-- {
+		h, err := l.readLogFileHeader(l.logNames[i])
+		if err == io.EOF || os.IsNotExist(err) {
+			start = i
+			continue
+		} else if err != nil {
+			return err
+		} else if h.Version != BinLogVersion {
+			//no event id in v1
+			break
+		}
+
+		start = i
+		if h.FirstEventID <= id+1 {
+			break
+		}
+	}
+
+	for _, name := range l.logNames[start:] {
+		fd, err := os.Open(path.Join(l.cfg.Path, name))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		err = ReadBinLogEvents(fd, func(e *BinLogEvent) error {
+			if e.ID <= id {
+				return nil
+			}
+			return f(e)
+		})
+		fd.Close()
+
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
+
+func (l *BinLog) readLogFileHeader(name string) (*BinLogHeader, error) {
+	fd, err := os.Open(path.Join(l.cfg.Path, name))
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	return readBinLogHeader(bufio.NewReaderSize(fd, binLogHeaderSize))
+}
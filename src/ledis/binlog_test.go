@@ -1,9 +1,14 @@
 package ledis
 
 import (
+	"bytes"
+	"encoding/binary"
+	"io"
 	"io/ioutil"
 	"os"
+	"path"
 	"testing"
+	"time"
 )
 
 func TestBinLog(t *testing.T) {
@@ -34,3 +39,404 @@ func TestBinLog(t *testing.T) {
 		t.Fatal(len(fs))
 	}
 }
+
+func TestBinLogEventID(t *testing.T) {
+	cfg := new(BinLogConfig)
+
+	cfg.MaxFileNum = 10
+	cfg.MaxFileSize = 1024
+	cfg.Path = "/tmp/ledis_binlog_id"
+
+	os.RemoveAll(cfg.Path)
+
+	b, err := NewBinLog(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := b.Log(make([]byte, 512), make([]byte, 512)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if id := b.LastEventID(); id != 6 {
+		t.Fatal(id)
+	}
+
+	b.Close()
+
+	//a new log file is created after reopen, the event id goes on
+	if b, err = NewBinLog(cfg); err != nil {
+		t.Fatal(err)
+	} else if id := b.LastEventID(); id != 6 {
+		t.Fatal(id)
+	}
+
+	if err := b.Log([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	b.Close()
+
+	var ids []uint64
+	for _, name := range b.LogNames() {
+		f, err := os.Open(path.Join(cfg.Path, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ReadBinLogEvents(f, func(e *BinLogEvent) error {
+			ids = append(ids, e.ID)
+			return nil
+		})
+		f.Close()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(ids) != 7 {
+		t.Fatal(ids)
+	}
+
+	for i, id := range ids {
+		if id != uint64(i+1) {
+			t.Fatal(ids)
+		}
+	}
+}
+
+func TestBinLogRecover(t *testing.T) {
+	cfg := new(BinLogConfig)
+
+	cfg.MaxFileNum = 10
+	cfg.MaxFileSize = 1024 * 1024
+	cfg.Path = "/tmp/ledis_binlog_recover"
+
+	os.RemoveAll(cfg.Path)
+
+	b, err := NewBinLog(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Log([]byte("a"), []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+
+	size := b.LogFilePos()
+	name := b.LogNames()[0]
+
+	b.Close()
+
+	//a partial event written when crashed
+	logPath := path.Join(cfg.Path, name)
+	f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	writeBinLogEvent(&buf, 0, 3, []byte("hello world"))
+	f.Write(buf.Bytes()[0 : buf.Len()-3])
+	f.Close()
+
+	if err := ReadBinLogEvents(bytes.NewReader(mustReadFile(t, logPath)), func(*BinLogEvent) error { return nil }); err != io.ErrUnexpectedEOF {
+		t.Fatal(err)
+	}
+
+	if b, err = NewBinLog(cfg); err != nil {
+		t.Fatal(err)
+	}
+	b.Close()
+
+	if st, err := os.Stat(logPath); err != nil {
+		t.Fatal(err)
+	} else if st.Size() != size {
+		t.Fatal(st.Size(), size)
+	}
+
+	if id := b.LastEventID(); id != 2 {
+		t.Fatal(id)
+	}
+
+	//a corrupted event
+	data := mustReadFile(t, logPath)
+	data[len(data)-1] ^= 0xFF
+	if err := ioutil.WriteFile(logPath, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ReadBinLogEvents(bytes.NewReader(data), func(*BinLogEvent) error { return nil }); err != errInvalidBinLogEvent {
+		t.Fatal(err)
+	}
+
+	if b, err = NewBinLog(cfg); err != nil {
+		t.Fatal(err)
+	}
+	b.Close()
+
+	if id := b.LastEventID(); id != 1 {
+		t.Fatal(id)
+	}
+}
+
+func TestBinLogV1(t *testing.T) {
+	var buf bytes.Buffer
+	for _, s := range []string{"a", "bc"} {
+		binary.Write(&buf, binary.BigEndian, uint32(100))
+		binary.Write(&buf, binary.BigEndian, uint32(len(s)))
+		buf.WriteString(s)
+	}
+
+	var events []string
+	err := ReadBinLogEvents(&buf, func(e *BinLogEvent) error {
+		if e.CreateTime != 100 || e.ID != 0 {
+			t.Fatal(e.CreateTime, e.ID)
+		}
+		events = append(events, string(e.Payload))
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	} else if len(events) != 2 || events[0] != "a" || events[1] != "bc" {
+		t.Fatal(events)
+	}
+}
+
+func mustReadFile(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestBinLogPurge(t *testing.T) {
+	cfg := new(BinLogConfig)
+
+	cfg.MaxFileNum = 10
+	cfg.MaxFileSize = 1024
+	cfg.Path = "/tmp/ledis_binlog_purge"
+
+	os.RemoveAll(cfg.Path)
+
+	b, err := NewBinLog(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	//every log makes a new file
+	for i := 0; i < 6; i++ {
+		if err := b.Log(make([]byte, 1024)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n, err := b.PurgeTo(3); err != nil {
+		t.Fatal(err)
+	} else if n != 2 {
+		t.Fatal(n)
+	} else if b.LogNames()[0] != b.FormatLogFileName(3) {
+		t.Fatal(b.LogNames())
+	}
+
+	//a slave is syncing file 4
+	var inUse int64 = 4
+	b.SetInUseFunc(func() int64 {
+		return inUse
+	})
+
+	if _, err := b.PurgeTo(5); err != ErrBinLogInUse {
+		t.Fatal(err)
+	} else if n := len(b.LogNames()); n != 4 {
+		t.Fatal(n)
+	}
+
+	//the current file is never purged
+	inUse = 0
+	if n, err := b.PurgeBefore(time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	} else if n != 3 {
+		t.Fatal(n)
+	} else if b.LogNames()[0] != b.LogFileName() {
+		t.Fatal(b.LogNames())
+	}
+
+	//retention by total size, checked when a new file is created, file 6 is in use
+	inUse = 6
+	b.SetRetention(0, 2048)
+	for i := 0; i < 3; i++ {
+		if err := b.Log(make([]byte, 1024)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if names := b.LogNames(); len(names) != 4 || names[0] != b.FormatLogFileName(6) {
+		t.Fatal(names)
+	}
+
+	inUse = 0
+	if err := b.Log(make([]byte, 1024)); err != nil {
+		t.Fatal(err)
+	}
+
+	//purged when the new file 10 is created
+	if names := b.LogNames(); len(names) != 2 || names[0] != b.FormatLogFileName(9) {
+		t.Fatal(names)
+	}
+
+	//retention by age
+	b.SetRetention(1, 0)
+	old := time.Now().Add(-time.Minute)
+	for _, name := range b.LogNames() {
+		os.Chtimes(path.Join(cfg.Path, name), old, old)
+	}
+
+	if err := b.Log(make([]byte, 1024)); err != nil {
+		t.Fatal(err)
+	}
+
+	if names := b.LogNames(); len(names) != 1 || names[0] != b.LogFileName() {
+		t.Fatal(names)
+	}
+
+	//max file num, the files in use are kept too
+	b.SetRetention(0, 0)
+	inUse = b.lastLogIndex
+	if err := b.SetLimit(1024, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := b.Log(make([]byte, 1024)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if names := b.LogNames(); len(names) != 4 || names[0] != b.FormatLogFileName(inUse) {
+		t.Fatal(names)
+	}
+
+	inUse = 0
+	if err := b.SetLimit(1024, 2); err != nil {
+		t.Fatal(err)
+	} else if names := b.LogNames(); len(names) != 2 || names[1] != b.LogFileName() {
+		t.Fatal(names)
+	}
+}
+
+func TestBinLogFsync(t *testing.T) {
+	cfg := new(BinLogConfig)
+
+	cfg.Path = "/tmp/ledis_binlog_fsync"
+	cfg.Fsync = "sometimes"
+
+	os.RemoveAll(cfg.Path)
+
+	if _, err := NewBinLog(cfg); err == nil {
+		t.Fatal("must error")
+	}
+
+	cfg.Fsync = ""
+	b, err := NewBinLog(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if b.Fsync() != FsyncEverySec {
+		t.Fatal(b.Fsync())
+	}
+
+	//synced in background once for many logs
+	b.Log([]byte("a"))
+	b.Log([]byte("b"))
+
+	if n := b.FsyncStats().Count; n != 0 {
+		t.Fatal(n)
+	}
+
+	if err := b.SyncDirty(); err != nil {
+		t.Fatal(err)
+	} else if err := b.SyncDirty(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := b.FsyncStats().Count; n != 1 {
+		t.Fatal(n)
+	}
+
+	//synced every log
+	if err := b.SetFsync(FsyncAlways); err != nil {
+		t.Fatal(err)
+	}
+
+	b.Log([]byte("c"))
+	b.Log([]byte("d"))
+
+	if st := b.FsyncStats(); st.Count != 3 || st.Max < st.Last || st.Total < st.Max {
+		t.Fatal(st)
+	}
+
+	//never synced before closed
+	if err := b.SetFsync(FsyncNo); err != nil {
+		t.Fatal(err)
+	}
+
+	b.Log([]byte("e"))
+	b.SyncDirty()
+
+	if n := b.FsyncStats().Count; n != 3 {
+		t.Fatal(n)
+	}
+}
+
+func TestBinLogBytesAfter(t *testing.T) {
+	cfg := new(BinLogConfig)
+
+	cfg.MaxFileSize = 1024
+	cfg.Path = "/tmp/ledis_binlog_bytes"
+
+	os.RemoveAll(cfg.Path)
+
+	b, err := NewBinLog(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	index := b.LogFileIndex()
+	pos := b.LogFilePos()
+
+	if n := b.BytesAfter(index, pos); n != 0 {
+		t.Fatal(n)
+	}
+
+	b.Log(make([]byte, 100))
+
+	if n := b.BytesAfter(index, pos); n != b.LogFilePos()-pos {
+		t.Fatal(n)
+	}
+
+	//rotated to a new file
+	b.Log(make([]byte, 1024))
+	b.Log(make([]byte, 100))
+
+	if b.LogFileIndex() == index {
+		t.Fatal("must rotate")
+	}
+
+	st, _ := os.Stat(b.FormatLogFilePath(index))
+	if n := b.BytesAfter(index, pos); n != st.Size()-pos+b.LogFilePos() {
+		t.Fatal(n)
+	}
+
+	if n := b.BytesAfter(b.LogFileIndex(), b.LogFilePos()); n != 0 {
+		t.Fatal(n)
+	}
+}
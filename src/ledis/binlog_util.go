@@ -1,9 +1,12 @@
 package ledis
 
 import (
+	"bufio"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/crc32"
+	"io"
 	"strconv"
 )
 
@@ -208,3 +211,170 @@ func formatDataKey(buf []byte, k []byte) ([]byte, error) {
 
 	return buf, nil
 }
+
+type BinLogHeader struct {
+	Version      uint32
+	FirstEventID uint64
+}
+
+//Size returns the header size in log file, format v1 has no header.
+func (h *BinLogHeader) Size() int64 {
+	if h.Version == 1 {
+		return 0
+	}
+	return binLogHeaderSize
+}
+
+func (h *BinLogHeader) Encode(w io.Writer) error {
+	buf := make([]byte, binLogHeaderSize)
+	copy(buf, binLogMagic)
+	binary.BigEndian.PutUint32(buf[8:], h.Version)
+	binary.BigEndian.PutUint64(buf[12:], h.FirstEventID)
+
+	_, err := w.Write(buf)
+	return err
+}
+
+//readBinLogHeader reads the header of log file or sync data, data without header is format v1,
+//returns io.EOF if no data, io.ErrUnexpectedEOF if the header is partial.
+func readBinLogHeader(rb *bufio.Reader) (*BinLogHeader, error) {
+	buf, err := rb.Peek(binLogHeaderSize)
+	if len(buf) == 0 && err == io.EOF {
+		return nil, io.EOF
+	}
+
+	n := len(buf)
+	if n > len(binLogMagic) {
+		n = len(binLogMagic)
+	}
+
+	if string(buf[0:n]) != binLogMagic[0:n] {
+		return &BinLogHeader{Version: 1}, nil
+	} else if err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	h := new(BinLogHeader)
+	h.Version = binary.BigEndian.Uint32(buf[8:])
+	h.FirstEventID = binary.BigEndian.Uint64(buf[12:])
+
+	if h.Version != BinLogVersion {
+		return nil, fmt.Errorf("invalid binlog version %d", h.Version)
+	}
+
+	rb.Discard(binLogHeaderSize)
+	return h, nil
+}
+
+type BinLogEvent struct {
+	CreateTime uint32
+
+	//0 for format v1
+	ID uint64
+
+	Payload []byte
+}
+
+type binLogEventReader struct {
+	r io.Reader
+
+	version uint32
+
+	head []byte
+	buf  []byte
+
+	e BinLogEvent
+
+	//size of the last event read
+	size int64
+}
+
+func newBinLogEventReader(r io.Reader, version uint32) *binLogEventReader {
+	er := new(binLogEventReader)
+	er.r = r
+	er.version = version
+
+	if version == 1 {
+		er.head = make([]byte, 8)
+	} else {
+		er.head = make([]byte, 16)
+	}
+	return er
+}
+
+//Read returns io.EOF if no more event, io.ErrUnexpectedEOF if the event is partial,
+//the event is only valid until next Read.
+func (er *binLogEventReader) Read() (*BinLogEvent, error) {
+	if _, err := io.ReadFull(er.r, er.head); err != nil {
+		return nil, err
+	}
+
+	var payloadLen uint32
+
+	e := &er.e
+	e.CreateTime = binary.BigEndian.Uint32(er.head)
+	if er.version == 1 {
+		payloadLen = binary.BigEndian.Uint32(er.head[4:])
+	} else {
+		e.ID = binary.BigEndian.Uint64(er.head[4:])
+		payloadLen = binary.BigEndian.Uint32(er.head[12:])
+	}
+
+	if payloadLen > uint32(MaxBinLogFileSize) {
+		return nil, errInvalidBinLogEvent
+	}
+
+	//payload and crc
+	n := int(payloadLen)
+	if er.version != 1 {
+		n += 4
+	}
+
+	if cap(er.buf) < n {
+		er.buf = make([]byte, n)
+	}
+	er.buf = er.buf[0:n]
+
+	if _, err := io.ReadFull(er.r, er.buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	e.Payload = er.buf[0:payloadLen]
+
+	if er.version != 1 {
+		crc := crc32.Update(crc32.ChecksumIEEE(er.head), crc32.IEEETable, e.Payload)
+		if crc != binary.BigEndian.Uint32(er.buf[payloadLen:]) {
+			return nil, errInvalidBinLogEvent
+		}
+	}
+
+	er.size = int64(len(er.head) + n)
+	return e, nil
+}
+
+//EventSize returns the size of the last event read
+func (er *binLogEventReader) EventSize() int64 {
+	return er.size
+}
+
+func writeBinLogEvent(w io.Writer, createTime uint32, id uint64, payload []byte) error {
+	var head [16]byte
+	binary.BigEndian.PutUint32(head[0:], createTime)
+	binary.BigEndian.PutUint64(head[4:], id)
+	binary.BigEndian.PutUint32(head[12:], uint32(len(payload)))
+
+	crc := crc32.Update(crc32.ChecksumIEEE(head[:]), crc32.IEEETable, payload)
+
+	if _, err := w.Write(head[:]); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(payload); err != nil {
+		return err
+	}
+
+	return binary.Write(w, binary.BigEndian, crc)
+}
@@ -85,7 +85,8 @@ func (c *Client) get() *Conn {
 
 func (c *Client) put(conn *Conn) {
 	c.Lock()
-	if c.conns.Len() >= c.cfg.MaxIdleConns {
+	if conn.pending > 0 || c.conns.Len() >= c.cfg.MaxIdleConns {
+		//a connection with unreceived replies can not be reused
 		c.Unlock()
 		conn.finalize()
 	} else {
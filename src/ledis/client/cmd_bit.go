@@ -0,0 +1,67 @@
+package client
+
+const (
+	OPand = "and"
+	OPor  = "or"
+	OPxor = "xor"
+	OPnot = "not"
+)
+
+//BGet returns nil if the key does not exist.
+func (c *Conn) BGet(key []byte) ([]byte, error) {
+	return bytesReply(c.Do("bget", key))
+}
+
+func (c *Conn) BDelete(key []byte) (int64, error) {
+	return Int64(c.Do("bdelete", key))
+}
+
+//BSetBit returns the original bit value.
+func (c *Conn) BSetBit(key []byte, offset int32, val uint8) (uint8, error) {
+	v, err := Int(c.Do("bsetbit", key, offset, val))
+	return uint8(v), err
+}
+
+func (c *Conn) BGetBit(key []byte, offset int32) (uint8, error) {
+	v, err := Int(c.Do("bgetbit", key, offset))
+	return uint8(v), err
+}
+
+func (c *Conn) BMSetBit(key []byte, args ...BitPair) (int64, error) {
+	a := make([]interface{}, 0, 1+2*len(args))
+	a = append(a, key)
+	for _, bp := range args {
+		a = append(a, bp.Pos, bp.Val)
+	}
+	return Int64(c.Do("bmsetbit", a...))
+}
+
+//BCount counts the bits set in [start, end].
+func (c *Conn) BCount(key []byte, start int32, end int32) (int32, error) {
+	v, err := Int(c.Do("bcount", key, start, end))
+	return int32(v), err
+}
+
+//BOpt applies the op (OPand, OPor, OPxor or OPnot) to the src keys
+//and stores the result in the dest key, it returns the bit length of the result.
+func (c *Conn) BOpt(op string, destKey []byte, srcKeys ...[]byte) (int32, error) {
+	a := append([]interface{}{op, destKey}, keysArgs(srcKeys)...)
+	v, err := Int(c.Do("bopt", a...))
+	return int32(v), err
+}
+
+func (c *Conn) BExpire(key []byte, duration int64) (int64, error) {
+	return Int64(c.Do("bexpire", key, duration))
+}
+
+func (c *Conn) BExpireAt(key []byte, when int64) (int64, error) {
+	return Int64(c.Do("bexpireat", key, when))
+}
+
+func (c *Conn) BTTL(key []byte) (int64, error) {
+	return Int64(c.Do("bttl", key))
+}
+
+func (c *Conn) BPersist(key []byte) (int64, error) {
+	return Int64(c.Do("bpersist", key))
+}
@@ -0,0 +1,85 @@
+package client
+
+func (c *Conn) HDel(key []byte, fields ...[]byte) (int64, error) {
+	return Int64(c.Do("hdel", append([]interface{}{key}, keysArgs(fields)...)...))
+}
+
+func (c *Conn) HExists(key []byte, field []byte) (int64, error) {
+	return Int64(c.Do("hexists", key, field))
+}
+
+//HGet returns nil if the field does not exist.
+func (c *Conn) HGet(key []byte, field []byte) ([]byte, error) {
+	return bytesReply(c.Do("hget", key, field))
+}
+
+func (c *Conn) HGetAll(key []byte) (map[string][]byte, error) {
+	v, err := ByteSlices(c.Do("hgetall", key))
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string][]byte, len(v)/2)
+	for i := 0; i+1 < len(v); i += 2 {
+		m[string(v[i])] = v[i+1]
+	}
+	return m, nil
+}
+
+func (c *Conn) HIncrBy(key []byte, field []byte, delta int64) (int64, error) {
+	return Int64(c.Do("hincrby", key, field, delta))
+}
+
+func (c *Conn) HKeys(key []byte) ([][]byte, error) {
+	return ByteSlices(c.Do("hkeys", key))
+}
+
+func (c *Conn) HLen(key []byte) (int64, error) {
+	return Int64(c.Do("hlen", key))
+}
+
+//HMGet returns nil for the field which does not exist.
+func (c *Conn) HMGet(key []byte, fields ...[]byte) ([][]byte, error) {
+	return ByteSlices(c.Do("hmget", append([]interface{}{key}, keysArgs(fields)...)...))
+}
+
+func (c *Conn) HMSet(key []byte, args ...FVPair) error {
+	a := make([]interface{}, 0, 1+2*len(args))
+	a = append(a, key)
+	for _, fv := range args {
+		a = append(a, fv.Field, fv.Value)
+	}
+	return statusReply(c.Do("hmset", a...))
+}
+
+func (c *Conn) HSet(key []byte, field []byte, value []byte) (int64, error) {
+	return Int64(c.Do("hset", key, field, value))
+}
+
+func (c *Conn) HVals(key []byte) ([][]byte, error) {
+	return ByteSlices(c.Do("hvals", key))
+}
+
+func (c *Conn) HClear(key []byte) (int64, error) {
+	return Int64(c.Do("hclear", key))
+}
+
+func (c *Conn) HMClear(keys ...[]byte) (int64, error) {
+	return Int64(c.Do("hmclear", keysArgs(keys)...))
+}
+
+func (c *Conn) HExpire(key []byte, duration int64) (int64, error) {
+	return Int64(c.Do("hexpire", key, duration))
+}
+
+func (c *Conn) HExpireAt(key []byte, when int64) (int64, error) {
+	return Int64(c.Do("hexpireat", key, when))
+}
+
+func (c *Conn) HTTL(key []byte) (int64, error) {
+	return Int64(c.Do("httl", key))
+}
+
+func (c *Conn) HPersist(key []byte) (int64, error) {
+	return Int64(c.Do("hpersist", key))
+}
@@ -0,0 +1,71 @@
+package client
+
+func (c *Conn) Decr(key []byte) (int64, error) {
+	return Int64(c.Do("decr", key))
+}
+
+func (c *Conn) DecrBy(key []byte, decrement int64) (int64, error) {
+	return Int64(c.Do("decrby", key, decrement))
+}
+
+func (c *Conn) Del(keys ...[]byte) (int64, error) {
+	return Int64(c.Do("del", keysArgs(keys)...))
+}
+
+func (c *Conn) Exists(key []byte) (int64, error) {
+	return Int64(c.Do("exists", key))
+}
+
+//Get returns nil if the key does not exist.
+func (c *Conn) Get(key []byte) ([]byte, error) {
+	return bytesReply(c.Do("get", key))
+}
+
+func (c *Conn) GetSet(key []byte, value []byte) ([]byte, error) {
+	return bytesReply(c.Do("getset", key, value))
+}
+
+func (c *Conn) Incr(key []byte) (int64, error) {
+	return Int64(c.Do("incr", key))
+}
+
+func (c *Conn) IncrBy(key []byte, increment int64) (int64, error) {
+	return Int64(c.Do("incrby", key, increment))
+}
+
+//MGet returns nil for the key which does not exist.
+func (c *Conn) MGet(keys ...[]byte) ([][]byte, error) {
+	return ByteSlices(c.Do("mget", keysArgs(keys)...))
+}
+
+func (c *Conn) MSet(args ...KVPair) error {
+	a := make([]interface{}, 0, 2*len(args))
+	for _, kv := range args {
+		a = append(a, kv.Key, kv.Value)
+	}
+	return statusReply(c.Do("mset", a...))
+}
+
+func (c *Conn) Set(key []byte, value []byte) error {
+	return statusReply(c.Do("set", key, value))
+}
+
+func (c *Conn) SetNX(key []byte, value []byte) (int64, error) {
+	return Int64(c.Do("setnx", key, value))
+}
+
+func (c *Conn) Expire(key []byte, duration int64) (int64, error) {
+	return Int64(c.Do("expire", key, duration))
+}
+
+func (c *Conn) ExpireAt(key []byte, when int64) (int64, error) {
+	return Int64(c.Do("expireat", key, when))
+}
+
+func (c *Conn) TTL(key []byte) (int64, error) {
+	return Int64(c.Do("ttl", key))
+}
+
+func (c *Conn) Persist(key []byte) (int64, error) {
+	return Int64(c.Do("persist", key))
+}
@@ -0,0 +1,56 @@
+package client
+
+//LIndex returns nil if the index is out of range.
+func (c *Conn) LIndex(key []byte, index int32) ([]byte, error) {
+	return bytesReply(c.Do("lindex", key, index))
+}
+
+func (c *Conn) LLen(key []byte) (int64, error) {
+	return Int64(c.Do("llen", key))
+}
+
+//LPop returns nil if the list is empty.
+func (c *Conn) LPop(key []byte) ([]byte, error) {
+	return bytesReply(c.Do("lpop", key))
+}
+
+func (c *Conn) LRange(key []byte, start int32, stop int32) ([][]byte, error) {
+	return ByteSlices(c.Do("lrange", key, start, stop))
+}
+
+func (c *Conn) LPush(key []byte, args ...[]byte) (int64, error) {
+	return Int64(c.Do("lpush", append([]interface{}{key}, keysArgs(args)...)...))
+}
+
+//RPop returns nil if the list is empty.
+func (c *Conn) RPop(key []byte) ([]byte, error) {
+	return bytesReply(c.Do("rpop", key))
+}
+
+func (c *Conn) RPush(key []byte, args ...[]byte) (int64, error) {
+	return Int64(c.Do("rpush", append([]interface{}{key}, keysArgs(args)...)...))
+}
+
+func (c *Conn) LClear(key []byte) (int64, error) {
+	return Int64(c.Do("lclear", key))
+}
+
+func (c *Conn) LMClear(keys ...[]byte) (int64, error) {
+	return Int64(c.Do("lmclear", keysArgs(keys)...))
+}
+
+func (c *Conn) LExpire(key []byte, duration int64) (int64, error) {
+	return Int64(c.Do("lexpire", key, duration))
+}
+
+func (c *Conn) LExpireAt(key []byte, when int64) (int64, error) {
+	return Int64(c.Do("lexpireat", key, when))
+}
+
+func (c *Conn) LTTL(key []byte) (int64, error) {
+	return Int64(c.Do("lttl", key))
+}
+
+func (c *Conn) LPersist(key []byte) (int64, error) {
+	return Int64(c.Do("lpersist", key))
+}
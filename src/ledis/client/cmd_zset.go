@@ -0,0 +1,153 @@
+package client
+
+import (
+	"math"
+	"strconv"
+)
+
+//scoreArg formats the score bound, math.MinInt64 and math.MaxInt64 mean -inf and +inf.
+func scoreArg(score int64) interface{} {
+	switch score {
+	case math.MinInt64:
+		return "-inf"
+	case math.MaxInt64:
+		return "+inf"
+	default:
+		return score
+	}
+}
+
+func scorePairsReply(reply interface{}, err error) ([]ScorePair, error) {
+	v, err := ByteSlices(reply, err)
+	if err != nil {
+		return nil, err
+	}
+
+	ps := make([]ScorePair, 0, len(v)/2)
+	for i := 0; i+1 < len(v); i += 2 {
+		score, err := strconv.ParseInt(string(v[i+1]), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ps = append(ps, ScorePair{Score: score, Member: v[i]})
+	}
+	return ps, nil
+}
+
+//rankReply returns -1 if the member does not exist.
+func rankReply(reply interface{}, err error) (int64, error) {
+	v, err := Int64(reply, err)
+	if err == ErrNil {
+		return -1, nil
+	}
+	return v, err
+}
+
+func (c *Conn) ZAdd(key []byte, args ...ScorePair) (int64, error) {
+	a := make([]interface{}, 0, 1+2*len(args))
+	a = append(a, key)
+	for _, sp := range args {
+		a = append(a, sp.Score, sp.Member)
+	}
+	return Int64(c.Do("zadd", a...))
+}
+
+func (c *Conn) ZCard(key []byte) (int64, error) {
+	return Int64(c.Do("zcard", key))
+}
+
+//ZCount counts the members with score in [min, max],
+//use math.MinInt64 and math.MaxInt64 for -inf and +inf.
+func (c *Conn) ZCount(key []byte, min int64, max int64) (int64, error) {
+	return Int64(c.Do("zcount", key, scoreArg(min), scoreArg(max)))
+}
+
+//ZIncrBy returns the new score of the member.
+func (c *Conn) ZIncrBy(key []byte, delta int64, member []byte) (int64, error) {
+	return Int64(c.Do("zincrby", key, delta, member))
+}
+
+func (c *Conn) ZRange(key []byte, start int, stop int) ([][]byte, error) {
+	return ByteSlices(c.Do("zrange", key, start, stop))
+}
+
+func (c *Conn) ZRangeWithScores(key []byte, start int, stop int) ([]ScorePair, error) {
+	return scorePairsReply(c.Do("zrange", key, start, stop, "withscores"))
+}
+
+//ZRangeByScore returns at most count members from offset with score in [min, max],
+//a negative count means all.
+func (c *Conn) ZRangeByScore(key []byte, min int64, max int64, offset int, count int) ([][]byte, error) {
+	return ByteSlices(c.Do("zrangebyscore", key, scoreArg(min), scoreArg(max), "limit", offset, count))
+}
+
+func (c *Conn) ZRangeByScoreWithScores(key []byte, min int64, max int64, offset int, count int) ([]ScorePair, error) {
+	return scorePairsReply(c.Do("zrangebyscore", key, scoreArg(min), scoreArg(max), "withscores", "limit", offset, count))
+}
+
+//ZRank returns -1 if the member does not exist.
+func (c *Conn) ZRank(key []byte, member []byte) (int64, error) {
+	return rankReply(c.Do("zrank", key, member))
+}
+
+func (c *Conn) ZRem(key []byte, members ...[]byte) (int64, error) {
+	return Int64(c.Do("zrem", append([]interface{}{key}, keysArgs(members)...)...))
+}
+
+func (c *Conn) ZRemRangeByRank(key []byte, start int, stop int) (int64, error) {
+	return Int64(c.Do("zremrangebyrank", key, start, stop))
+}
+
+func (c *Conn) ZRemRangeByScore(key []byte, min int64, max int64) (int64, error) {
+	return Int64(c.Do("zremrangebyscore", key, scoreArg(min), scoreArg(max)))
+}
+
+func (c *Conn) ZRevRange(key []byte, start int, stop int) ([][]byte, error) {
+	return ByteSlices(c.Do("zrevrange", key, start, stop))
+}
+
+func (c *Conn) ZRevRangeWithScores(key []byte, start int, stop int) ([]ScorePair, error) {
+	return scorePairsReply(c.Do("zrevrange", key, start, stop, "withscores"))
+}
+
+//ZRevRank returns -1 if the member does not exist.
+func (c *Conn) ZRevRank(key []byte, member []byte) (int64, error) {
+	return rankReply(c.Do("zrevrank", key, member))
+}
+
+func (c *Conn) ZRevRangeByScore(key []byte, max int64, min int64, offset int, count int) ([][]byte, error) {
+	return ByteSlices(c.Do("zrevrangebyscore", key, scoreArg(max), scoreArg(min), "limit", offset, count))
+}
+
+func (c *Conn) ZRevRangeByScoreWithScores(key []byte, max int64, min int64, offset int, count int) ([]ScorePair, error) {
+	return scorePairsReply(c.Do("zrevrangebyscore", key, scoreArg(max), scoreArg(min), "withscores", "limit", offset, count))
+}
+
+//ZScore returns ErrNil if the member does not exist.
+func (c *Conn) ZScore(key []byte, member []byte) (int64, error) {
+	return Int64(c.Do("zscore", key, member))
+}
+
+func (c *Conn) ZClear(key []byte) (int64, error) {
+	return Int64(c.Do("zclear", key))
+}
+
+func (c *Conn) ZMClear(keys ...[]byte) (int64, error) {
+	return Int64(c.Do("zmclear", keysArgs(keys)...))
+}
+
+func (c *Conn) ZExpire(key []byte, duration int64) (int64, error) {
+	return Int64(c.Do("zexpire", key, duration))
+}
+
+func (c *Conn) ZExpireAt(key []byte, when int64) (int64, error) {
+	return Int64(c.Do("zexpireat", key, when))
+}
+
+func (c *Conn) ZTTL(key []byte) (int64, error) {
+	return Int64(c.Do("zttl", key))
+}
+
+func (c *Conn) ZPersist(key []byte) (int64, error) {
+	return Int64(c.Do("zpersist", key))
+}
@@ -0,0 +1,166 @@
+package client
+
+type KVPair struct {
+	Key   []byte
+	Value []byte
+}
+
+type FVPair struct {
+	Field []byte
+	Value []byte
+}
+
+type ScorePair struct {
+	Score  int64
+	Member []byte
+}
+
+type BitPair struct {
+	Pos int32
+	Val uint8
+}
+
+//bytesReply is like Bytes, but returns nil, nil for nil reply
+func bytesReply(reply interface{}, err error) ([]byte, error) {
+	v, err := Bytes(reply, err)
+	if err == ErrNil {
+		return nil, nil
+	}
+	return v, err
+}
+
+func statusReply(reply interface{}, err error) error {
+	_, err = String(reply, err)
+	return err
+}
+
+func keysArgs(keys [][]byte) []interface{} {
+	args := make([]interface{}, len(keys))
+	for i, k := range keys {
+		args[i] = k
+	}
+	return args
+}
+
+func (c *Conn) Ping() error {
+	return statusReply(c.Do("ping"))
+}
+
+func (c *Conn) Echo(message []byte) ([]byte, error) {
+	return Bytes(c.Do("echo", message))
+}
+
+//Select changes the db of the connection.
+func (c *Conn) Select(index int) error {
+	return statusReply(c.Do("select", index))
+}
+
+func (c *Conn) Shutdown() error {
+	return statusReply(c.Do("shutdown"))
+}
+
+//Compact compacts all data in all dbs.
+func (c *Conn) Compact() error {
+	return statusReply(c.Do("compact"))
+}
+
+//CompactDB compacts all data in the db.
+func (c *Conn) CompactDB(index int) error {
+	return statusReply(c.Do("compact", index))
+}
+
+//CompactType compacts the data of the type (kv, list, hash, zset or bit) in the db.
+func (c *Conn) CompactType(index int, dataType string) error {
+	return statusReply(c.Do("compact", index, dataType))
+}
+
+//Backup copies all data to the dir under data_dir/backup on the server host, which can be restored by ledis-restore.
+func (c *Conn) Backup(dir string) error {
+	return statusReply(c.Do("backup", dir))
+}
+
+//Wait waits numSlaves slaves to ack current binlog position for timeout milliseconds, 0 means no timeout,
+//returns the number of slaves acked.
+func (c *Conn) Wait(numSlaves int, timeout int) (int64, error) {
+	return Int64(c.Do("wait", numSlaves, timeout))
+}
+
+//Role returns the replication role, see ROLE in commands doc for the reply.
+func (c *Conn) Role() ([]interface{}, error) {
+	return Values(c.Do("role"))
+}
+
+//PurgeBinLogsTo purges the binlog files before the index, returns the number of purged files.
+func (c *Conn) PurgeBinLogsTo(index int64) (int64, error) {
+	return Int64(c.Do("purge", "binlogs", "to", index))
+}
+
+//PurgeBinLogsBefore purges the binlog files last modified before the unix time,
+//returns the number of purged files.
+func (c *Conn) PurgeBinLogsBefore(t int64) (int64, error) {
+	return Int64(c.Do("purge", "binlogs", "before", t))
+}
+
+//Info returns the server information of the section (server, clients or storage),
+//all sections if section is empty.
+func (c *Conn) Info(section string) (string, error) {
+	if len(section) == 0 {
+		return String(c.Do("info"))
+	}
+	return String(c.Do("info", section))
+}
+
+//ClientList returns the connected clients, one per line.
+func (c *Conn) ClientList() (string, error) {
+	return String(c.Do("client", "list"))
+}
+
+//ClientKill closes the client connection with the addr or id.
+func (c *Conn) ClientKill(addrOrID string) error {
+	return statusReply(c.Do("client", "kill", addrOrID))
+}
+
+//ClientSetName sets the name of the connection.
+func (c *Conn) ClientSetName(name string) error {
+	return statusReply(c.Do("client", "setname", name))
+}
+
+func (c *Conn) ClientGetName() (string, error) {
+	v, err := String(c.Do("client", "getname"))
+	if err == ErrNil {
+		return "", nil
+	}
+	return v, err
+}
+
+//ConfigGet returns the config parameters matching the pattern.
+func (c *Conn) ConfigGet(pattern string) (map[string]string, error) {
+	v, err := Strings(c.Do("config", "get", pattern))
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]string, len(v)/2)
+	for i := 0; i+1 < len(v); i += 2 {
+		m[v[i]] = v[i+1]
+	}
+	return m, nil
+}
+
+func (c *Conn) ConfigSet(name string, value string) error {
+	return statusReply(c.Do("config", "set", name, value))
+}
+
+func (c *Conn) ConfigRewrite() error {
+	return statusReply(c.Do("config", "rewrite"))
+}
+
+//SlaveOf makes the server a slave of the master at host:port.
+func (c *Conn) SlaveOf(host string, port int) error {
+	return statusReply(c.Do("slaveof", host, port))
+}
+
+//SlaveOfNoOne stops the replication and makes the server a master.
+func (c *Conn) SlaveOfNoOne() error {
+	return statusReply(c.Do("slaveof", "no", "one"))
+}
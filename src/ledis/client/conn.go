@@ -16,6 +16,8 @@ type Error string
 
 func (err Error) Error() string { return string(err) }
 
+var errNoPending = errors.New("ledis: no pending reply to receive")
+
 type Conn struct {
 	client *Client
 
@@ -25,6 +27,9 @@ type Conn struct {
 
 	lastActive time.Time
 
+	//number of sent commands whose replies are not received yet
+	pending int
+
 	// Scratch space for formatting argument length.
 	// '*' or '$', length, "\r\n"
 	lenScratch [32]byte
@@ -37,30 +42,110 @@ func (c *Conn) Close() {
 	c.client.put(c)
 }
 
+// Do sends a command to the server and returns the received reply.
+//
+// If there are pending replies of commands sent by Send, Do receives them first
+// and returns the first error reply among them if existed, with the reply of this command.
+//
+// Do with an empty cmd flushes the sent commands and returns all pending replies as []interface{}.
 func (c *Conn) Do(cmd string, args ...interface{}) (interface{}, error) {
 	if err := c.connect(); err != nil {
 		return nil, err
 	}
 
-	if err := c.writeCommand(cmd, args); err != nil {
+	if cmd != "" {
+		if err := c.writeCommand(cmd, args); err != nil {
+			c.finalize()
+			return nil, err
+		}
+	}
+
+	if err := c.bw.Flush(); err != nil {
 		c.finalize()
 		return nil, err
 	}
 
+	pending := c.pending
+	c.pending = 0
+
+	if cmd == "" {
+		replies := make([]interface{}, pending)
+		for i := range replies {
+			if reply, err := c.readReply(); err != nil {
+				c.finalize()
+				return nil, err
+			} else {
+				replies[i] = reply
+			}
+		}
+		return replies, nil
+	}
+
+	var err error
+	var reply interface{}
+	for i := 0; i <= pending; i++ {
+		var e error
+		if reply, e = c.readReply(); e != nil {
+			c.finalize()
+			return nil, e
+		}
+
+		if e, ok := reply.(Error); ok && err == nil {
+			err = e
+		}
+	}
+
+	return reply, err
+}
+
+// Send writes the command to the buffer without waiting the reply.
+// The buffer is sent when it is full or Flush or Do is called, use Receive to get the reply.
+func (c *Conn) Send(cmd string, args ...interface{}) error {
+	if err := c.connect(); err != nil {
+		return err
+	}
+
+	if err := c.writeCommand(cmd, args); err != nil {
+		c.finalize()
+		return err
+	}
+
+	c.pending++
+	return nil
+}
+
+// Flush sends the buffered commands to the server.
+func (c *Conn) Flush() error {
+	if err := c.connect(); err != nil {
+		return err
+	}
+
 	if err := c.bw.Flush(); err != nil {
 		c.finalize()
-		return nil, err
+		return err
+	}
+
+	return nil
+}
+
+// Receive returns the next reply of the commands sent by Send, in order.
+func (c *Conn) Receive() (interface{}, error) {
+	if c.pending == 0 {
+		return nil, errNoPending
 	}
 
-	if reply, err := c.readReply(); err != nil {
+	reply, err := c.readReply()
+	if err != nil {
 		c.finalize()
 		return nil, err
+	}
+
+	c.pending--
+
+	if e, ok := reply.(Error); ok {
+		return reply, e
 	} else {
-		if e, ok := reply.(Error); ok {
-			return reply, e
-		} else {
-			return reply, nil
-		}
+		return reply, nil
 	}
 }
 
@@ -69,6 +154,8 @@ func (c *Conn) finalize() {
 		c.c.Close()
 		c.c = nil
 	}
+
+	c.pending = 0
 }
 
 func (c *Conn) connect() error {
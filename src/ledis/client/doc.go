@@ -35,6 +35,49 @@
 //     //connection send command
 //     conn.Do("ping")
 //
+// Pipelining
+//
+// Connection supports pipelining with Send, Flush and Receive functions.
+// Send writes the command to the buffer, Flush sends all buffered commands to the server,
+// and Receive reads a reply in the sent order.
+//
+//     conn := c.Get()
+//     defer conn.Close()
+//
+//     conn.Send("set", "a", "1")
+//     conn.Send("get", "a")
+//     conn.Flush()
+//
+//     conn.Receive() // reply of set
+//     conn.Receive() // reply of get
+//
+// Do with an empty command flushes and returns all pending replies.
+//
+//     conn.Send("incr", "a")
+//     conn.Send("incr", "b")
+//     replies, err := conn.Do("")
+//
+// A connection with unreceived replies is closed instead of put back to the pool when Close.
+//
+// Typed API
+//
+// Connection has a typed method for every ledis command, named after the command.
+// Typed methods are in cmd_*.go, the same as ledis server, and must be added
+// together with a new registered command.
+//
+//     conn := c.Get()
+//     defer conn.Close()
+//
+//     err := conn.Set([]byte("a"), []byte("1"))
+//
+//     n, err := conn.Incr([]byte("a"))
+//
+//     m, err := conn.HGetAll([]byte("hkey"))
+//
+//     pairs, err := conn.ZRangeWithScores([]byte("zkey"), 0, -1)
+//
+// A nil bulk reply is returned as a nil slice, e.g, Get for a key which does not exist.
+//
 // Reply Helper
 //
 // You can use reply helper to convert a reply to a specific type.
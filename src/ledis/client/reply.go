@@ -255,3 +255,33 @@ func Strings(reply interface{}, err error) ([]string, error) {
 	}
 	return nil, fmt.Errorf("ledis: unexpected type for Strings, got type %T", reply)
 }
+
+// ByteSlices is a helper that converts an array command reply to a [][]byte.
+// If err is not equal to nil, then ByteSlices returns nil, err. Nil array
+// items are converted to nil in the output slice. If one of the array items
+// is not a bulk string or nil, then ByteSlices returns an error.
+func ByteSlices(reply interface{}, err error) ([][]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	switch reply := reply.(type) {
+	case []interface{}:
+		result := make([][]byte, len(reply))
+		for i := range reply {
+			if reply[i] == nil {
+				continue
+			}
+			p, ok := reply[i].([]byte)
+			if !ok {
+				return nil, fmt.Errorf("ledis: unexpected element type for ByteSlices, got type %T", reply[i])
+			}
+			result[i] = p
+		}
+		return result, nil
+	case nil:
+		return nil, ErrNil
+	case Error:
+		return nil, reply
+	}
+	return nil, fmt.Errorf("ledis: unexpected type for ByteSlices, got type %T", reply)
+}
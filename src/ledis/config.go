@@ -2,32 +2,59 @@ package ledis
 
 import (
 	"github.com/siddontang/copier"
-	"leveldb"
 	"path"
+	"store"
 )
 
 type Config struct {
 	DataDir string `json:"data_dir"`
 
 	DB struct {
+		//store driver name, goleveldb, memory or leveldb (build with -tags leveldb)
+		Name string `json:"name"`
+
 		Compression     bool `json:"compression"`
 		BlockSize       int  `json:"block_size"`
 		WriteBufferSize int  `json:"write_buffer_size"`
 		CacheSize       int  `json:"cache_size"`
 		MaxOpenFiles    int  `json:"max_open_files"`
+
+		//bloom filter bits per key, default 10
+		BloomBits int `json:"bloom_bits"`
+
+		//number of keys between restart points for delta encoding of keys, default 16
+		BlockRestartInterval int `json:"block_restart_interval"`
+
+		//check data aggressively, stop on any corruption
+		ParanoidChecks bool `json:"paranoid_checks"`
+
+		//sync every write to disk, safe but slow
+		Sync bool `json:"sync"`
 	} `json:"db"`
 
 	BinLog struct {
 		Use         bool `json:"use"`
 		MaxFileSize int  `json:"max_file_size"`
 		MaxFileNum  int  `json:"max_file_num"`
+
+		//purge the log files last modified more than n seconds ago, 0 means no limit
+		MaxAge int `json:"max_age"`
+
+		//purge the oldest log files when the total size exceeds n bytes, 0 means no limit
+		MaxTotalSize int `json:"max_total_size"`
+
+		//when to fsync binlog, always (every commit), everysec (in background) or no (by OS), default everysec
+		Fsync string `json:"fsync"`
 	} `json:"binlog"`
+
+	//how many times per second to retire expired keys
+	ExpireHz int `json:"expire_hz"`
 }
 
-func (cfg *Config) NewDBConfig() *leveldb.Config {
+func (cfg *Config) NewDBConfig() *store.Config {
 	dbPath := path.Join(cfg.DataDir, "data")
 
-	dbCfg := new(leveldb.Config)
+	dbCfg := new(store.Config)
 	copier.Copy(dbCfg, &cfg.DB)
 	dbCfg.Path = dbPath
 	return dbCfg
@@ -40,6 +40,17 @@ var (
 	}
 )
 
+var (
+	//user visible data types, used by compact and etc.
+	DataTypeByName = map[string]byte{
+		"kv":   KVType,
+		"list": ListType,
+		"hash": HashType,
+		"zset": ZSetType,
+		"bit":  BitType,
+	}
+)
+
 const (
 	defaultScanCount int = 10
 )
@@ -50,6 +61,7 @@ var (
 	errHashFieldSize  = errors.New("invalid hash field size")
 	errZSetMemberSize = errors.New("invalid zset member size")
 	errExpireValue    = errors.New("invalid expire value")
+	errDataType       = errors.New("invalid data type")
 )
 
 const (
@@ -67,6 +79,11 @@ const (
 
 	//max value size
 	MaxValueSize int = 10 * 1024 * 1024
+
+	//max times per second to retire expired keys
+	MaxExpireHz int = 100
+
+	DefaultExpireHz int = 1
 )
 
 var (
@@ -4,18 +4,54 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"github.com/siddontang/go-snappy/snappy"
-	"leveldb"
+	"hash/crc32"
 	"io"
 	"os"
+	"runtime"
+	"store"
+	"time"
 )
 
-//dump format
+//dump format v2
+// header: magic "LEDISDMP"|version(uint16)|dbNumber(uint16)
+//   |fileIndex(int64)|filePos(int64)|createTime(int64)|crc32(uint32)
+// blocks: dataLen(uint32)|data|crc32(uint32)......
+//   data is snappy compressed records keylen(uint16)|key|valuelen(uint32)|value......
+// end: 0(uint32)|record count(int64)|crc32(uint32)
+//
+//all integers are bigendian, crc32 is IEEE of the header fields after magic,
+//the block data and the record count, a dump without end is truncated.
+//
+//dump format v1, can still be loaded
 // fileIndex(bigendian int64)|filePos(bigendian int64)
-// |keylen(bigendian int32)|key|valuelen(bigendian int32)|value......
+// |keylen(bigendian uint16)|key|valuelen(bigendian uint32)|value......
 //
 //key and value are both compressed for fast transfer dump on network using snappy
 
+const (
+	dumpMagic = "LEDISDMP"
+
+	DumpVersion uint16 = 2
+
+	//records are flushed as a block when the block is bigger than this
+	dumpBlockSize = 64 * 1024
+
+	//a block larger than this must be corrupted
+	maxDumpBlockSize = 64 * 1024 * 1024
+)
+
+var (
+	ErrDumpCorrupted = errors.New("dump corrupted")
+	ErrDumpTruncated = errors.New("dump truncated")
+)
+
+//a dump started within this time after the running dumps' snapshot shares it,
+//otherwise it uses a new snapshot, so the data of a later dump is not too old.
+var snapshotShareTime = 10 * time.Second
+
 type MasterInfo struct {
 	LogFileIndex int64
 	LogPos       int64
@@ -46,6 +82,143 @@ func (m *MasterInfo) ReadFrom(r io.Reader) error {
 	return nil
 }
 
+type dumpHeader struct {
+	Version    uint16
+	DBNumber   uint16
+	Info       MasterInfo
+	CreateTime int64
+}
+
+//header size after magic, without crc
+const dumpHeaderSize = 2 + 2 + 8 + 8 + 8
+
+func (h *dumpHeader) encode(w io.Writer) error {
+	buf := make([]byte, dumpHeaderSize+4)
+	binary.BigEndian.PutUint16(buf[0:], h.Version)
+	binary.BigEndian.PutUint16(buf[2:], h.DBNumber)
+	binary.BigEndian.PutUint64(buf[4:], uint64(h.Info.LogFileIndex))
+	binary.BigEndian.PutUint64(buf[12:], uint64(h.Info.LogPos))
+	binary.BigEndian.PutUint64(buf[20:], uint64(h.CreateTime))
+	binary.BigEndian.PutUint32(buf[dumpHeaderSize:], crc32.ChecksumIEEE(buf[0:dumpHeaderSize]))
+
+	if _, err := io.WriteString(w, dumpMagic); err != nil {
+		return err
+	}
+
+	_, err := w.Write(buf)
+	return err
+}
+
+//decode reads the header after magic
+func (h *dumpHeader) decode(r io.Reader) error {
+	buf := make([]byte, dumpHeaderSize+4)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return dumpReadError(err)
+	}
+
+	if crc32.ChecksumIEEE(buf[0:dumpHeaderSize]) != binary.BigEndian.Uint32(buf[dumpHeaderSize:]) {
+		return ErrDumpCorrupted
+	}
+
+	h.Version = binary.BigEndian.Uint16(buf[0:])
+	h.DBNumber = binary.BigEndian.Uint16(buf[2:])
+	h.Info.LogFileIndex = int64(binary.BigEndian.Uint64(buf[4:]))
+	h.Info.LogPos = int64(binary.BigEndian.Uint64(buf[12:]))
+	h.CreateTime = int64(binary.BigEndian.Uint64(buf[20:]))
+
+	if h.Version != DumpVersion {
+		return fmt.Errorf("invalid dump version %d", h.Version)
+	} else if h.DBNumber > uint16(MaxDBNumber) {
+		return fmt.Errorf("dump has %d dbs, but only %d supported", h.DBNumber, MaxDBNumber)
+	}
+
+	return nil
+}
+
+//an unexpected EOF in dump means the dump is not completed
+func dumpReadError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrDumpTruncated
+	}
+	return err
+}
+
+type dumpWriter struct {
+	w *bufio.Writer
+
+	block bytes.Buffer
+
+	compressBuf []byte
+
+	num int64
+}
+
+func newDumpWriter(w io.Writer) *dumpWriter {
+	d := new(dumpWriter)
+	d.w = bufio.NewWriterSize(w, 4096)
+	d.compressBuf = make([]byte, 4096)
+	return d
+}
+
+func (d *dumpWriter) Put(key []byte, value []byte) error {
+	var buf [4]byte
+
+	binary.BigEndian.PutUint16(buf[0:], uint16(len(key)))
+	d.block.Write(buf[0:2])
+	d.block.Write(key)
+
+	binary.BigEndian.PutUint32(buf[0:], uint32(len(value)))
+	d.block.Write(buf[0:4])
+	d.block.Write(value)
+
+	d.num++
+
+	if d.block.Len() >= dumpBlockSize {
+		return d.flushBlock()
+	}
+	return nil
+}
+
+func (d *dumpWriter) flushBlock() error {
+	if d.block.Len() == 0 {
+		return nil
+	}
+
+	data, err := snappy.Encode(d.compressBuf, d.block.Bytes())
+	if err != nil {
+		return err
+	}
+	d.compressBuf = data[0:cap(data)]
+	d.block.Reset()
+
+	if err = binary.Write(d.w, binary.BigEndian, uint32(len(data))); err != nil {
+		return err
+	}
+
+	if _, err = d.w.Write(data); err != nil {
+		return err
+	}
+
+	return binary.Write(d.w, binary.BigEndian, crc32.ChecksumIEEE(data))
+}
+
+//Close writes the remaining records and the end marker
+func (d *dumpWriter) Close() error {
+	if err := d.flushBlock(); err != nil {
+		return err
+	}
+
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[4:], uint64(d.num))
+	binary.BigEndian.PutUint32(buf[12:], crc32.ChecksumIEEE(buf[4:12]))
+
+	if _, err := d.w.Write(buf[:]); err != nil {
+		return err
+	}
+
+	return d.w.Flush()
+}
+
 func (l *Ledis) DumpFile(path string) error {
 	f, err := os.Create(path)
 	if err != nil {
@@ -56,94 +229,621 @@ func (l *Ledis) DumpFile(path string) error {
 	return l.Dump(f)
 }
 
-func (l *Ledis) Dump(w io.Writer) error {
-	var sp *leveldb.Snapshot
+//snapshot returns a snapshot of the store and the matching binlog position
+func (l *Ledis) snapshot() (*store.Snapshot, *MasterInfo, error) {
+	var sp *store.Snapshot
+	var err error
 	var m *MasterInfo = new(MasterInfo)
 	if l.binlog == nil {
-		sp = l.ldb.NewSnapshot()
+		sp, err = l.ldb.NewSnapshot()
 	} else {
 		l.Lock()
-		sp = l.ldb.NewSnapshot()
+		sp, err = l.ldb.NewSnapshot()
 		m.LogFileIndex = l.binlog.LogFileIndex()
 		m.LogPos = l.binlog.LogFilePos()
 		l.Unlock()
 	}
 
-	var err error
+	if err != nil {
+		return nil, nil, err
+	}
+	return sp, m, nil
+}
+
+//sharedSnapshot is a snapshot shared by the concurrent dumps,
+//closed after all the dumps finish.
+type sharedSnapshot struct {
+	sp      *store.Snapshot
+	info    MasterInfo
+	ref     int
+	created time.Time
+}
 
-	wb := bufio.NewWriterSize(w, 4096)
-	if err = m.WriteTo(wb); err != nil {
+//acquireSnapshot returns the snapshot of the running dumps if it is not older than snapshotShareTime,
+//or a new one.
+func (l *Ledis) acquireSnapshot() (*sharedSnapshot, error) {
+	l.snapLock.Lock()
+	defer l.snapLock.Unlock()
+
+	if l.snap == nil || time.Since(l.snap.created) > snapshotShareTime {
+		sp, m, err := l.snapshot()
+		if err != nil {
+			return nil, err
+		}
+		//the old one is closed by its last dump
+		l.snap = &sharedSnapshot{sp: sp, info: *m, created: time.Now()}
+	}
+
+	l.snap.ref++
+	return l.snap, nil
+}
+
+func (l *Ledis) releaseSnapshot(s *sharedSnapshot) {
+	l.snapLock.Lock()
+	defer l.snapLock.Unlock()
+
+	s.ref--
+	if s.ref == 0 {
+		s.sp.Close()
+		if l.snap == s {
+			l.snap = nil
+		}
+	}
+}
+
+//Dump writes all data in dump format v2
+func (l *Ledis) Dump(w io.Writer) error {
+	return l.DumpWithFilter(w, nil)
+}
+
+//DumpWithFilter writes the data matched by the filter in dump format v2,
+//all data if filter is nil.
+//
+//The concurrent dumps share the same snapshot, a dump started when others are running
+//has the data and binlog position of the running ones, unless their snapshot is older than 10 seconds.
+func (l *Ledis) DumpWithFilter(w io.Writer, f *DumpFilter) error {
+	return l.dump(w, f, nil, nil)
+}
+
+//DumpAfter writes the data after the key in dump format v2, all data if key is nil.
+//A broken dump can be resumed from the last loaded key with a new snapshot,
+//the data is consistent after the binlog from the position of the first dump is replicated.
+//
+//If started is not nil, it is called with the binlog position of the dump before any data is written.
+func (l *Ledis) DumpAfter(w io.Writer, key []byte, started func(info *MasterInfo)) error {
+	return l.dump(w, nil, key, started)
+}
+
+func (l *Ledis) dump(w io.Writer, f *DumpFilter, after []byte, started func(info *MasterInfo)) error {
+	s, err := l.acquireSnapshot()
+	if err != nil {
+		return err
+	}
+	defer l.releaseSnapshot(s)
+
+	if started != nil {
+		info := s.info
+		started(&info)
+	}
+
+	d := newDumpWriter(w)
+	if err = newDumpHeader(&s.info).encode(d.w); err != nil {
 		return err
 	}
 
-	it := sp.NewIterator()
-	it.SeekToFirst()
+	it := s.sp.NewIterator()
+	defer it.Close()
 
-	compressBuf := make([]byte, 4096)
+	if after == nil {
+		it.SeekToFirst()
+	} else if it.Seek(after); it.Valid() && bytes.Equal(it.RawKey(), after) {
+		it.Next()
+	}
 
-	var key []byte
-	var value []byte
+	//raw key and value are copied into the block only
 	for ; it.Valid(); it.Next() {
-		key = it.Key()
-		value = it.Value()
+		key := it.RawKey()
 
-		if key, err = snappy.Encode(compressBuf, key); err != nil {
-			return err
+		//the meta keys out of all dbs are not dumped
+		if key[0] >= MaxDBNumber {
+			break
 		}
 
-		if err = binary.Write(wb, binary.BigEndian, uint16(len(key))); err != nil {
-			return err
+		if !f.Match(key) {
+			continue
 		}
 
-		if _, err = wb.Write(key); err != nil {
+		if err = d.Put(key, it.RawValue()); err != nil {
 			return err
 		}
+	}
 
-		if value, err = snappy.Encode(compressBuf, value); err != nil {
-			return err
+	return d.Close()
+}
+
+func newDumpHeader(m *MasterInfo) *dumpHeader {
+	return &dumpHeader{
+		Version:    DumpVersion,
+		DBNumber:   uint16(MaxDBNumber),
+		Info:       *m,
+		CreateTime: time.Now().Unix(),
+	}
+}
+
+//LoadDumpFile verifies the whole dump file first, then loads it,
+//so nothing is loaded if the file is corrupted or truncated.
+func (l *Ledis) LoadDumpFile(path string) (*MasterInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if _, err = VerifyDump(f); err != nil {
+		return nil, err
+	} else if _, err = f.Seek(0, os.SEEK_SET); err != nil {
+		return nil, err
+	}
+
+	return l.LoadDump(f)
+}
+
+//VerifyDump reads the whole dump without loading, checks the checksums and the end of format v2.
+func VerifyDump(r io.Reader) (*MasterInfo, error) {
+	d, err := newDumpReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = d.ForEach(func(key []byte, value []byte) error {
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	info := d.header.Info
+	return &info, nil
+}
+
+//LoadDump loads the dump in format v2 or v1 as it is read,
+//the data loaded before an error is found is kept, so the caller should flush the data
+//if ErrDumpCorrupted is returned, or verify a dump file by VerifyDump before loading.
+func (l *Ledis) LoadDump(r io.Reader) (*MasterInfo, error) {
+	return l.LoadDumpWithFilter(r, nil)
+}
+
+//LoadDumpWithFilter loads the data matched by the filter in the dump,
+//all data if filter is nil, the matched keys replace the existing ones.
+func (l *Ledis) LoadDumpWithFilter(r io.Reader, f *DumpFilter) (*MasterInfo, error) {
+	return l.LoadDumpWithOptions(r, &LoadDumpOptions{Filter: f})
+}
+
+type LoadDumpOptions struct {
+	//only load the data matched, all data if nil,
+	//every matched key is cleared before loading, so its old data does not mix with the dump.
+	Filter *DumpFilter
+
+	//do not log every loaded key to binlog, but purge all binlogs after loading,
+	//the slaves of this server have to do a fullsync then.
+	BinLogCheckpoint bool
+
+	//called after every batch is committed with the binlog position of the dump
+	//and the last loaded key, an error stops loading.
+	Checkpoint func(info *MasterInfo, lastKey []byte) error
+}
+
+//LoadDumpWithOptions loads the dump with large write batches,
+//the blocks of dump format v2 are decompressed in parallel with the writes.
+func (l *Ledis) LoadDumpWithOptions(r io.Reader, opts *LoadDumpOptions) (*MasterInfo, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	d, err := newDumpReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	ld := newDumpLoader(l, l.binlog != nil && !opts.BinLogCheckpoint)
+	defer ld.Close()
+
+	if opts.Filter != nil {
+		ld.cleared = make(map[string]struct{})
+	}
+
+	if opts.Checkpoint != nil {
+		ld.checkpoint = func(lastKey []byte) error {
+			return opts.Checkpoint(&d.header.Info, lastKey)
 		}
+	}
 
-		if err = binary.Write(wb, binary.BigEndian, uint32(len(value))); err != nil {
-			return err
+	err = d.ForEach(func(key []byte, value []byte) error {
+		if !opts.Filter.Match(key) {
+			return nil
+		}
+		return ld.Put(key, value)
+	})
+
+	if err == nil {
+		err = ld.Commit()
+	}
+
+	if l.binlog != nil && opts.BinLogCheckpoint {
+		//checkpoint even if failed, some data may be loaded without binlog
+		if e := l.binlog.Checkpoint(); e != nil && err == nil {
+			err = e
 		}
 
-		if _, err = wb.Write(value); err != nil {
+		//the slaves find they have to fullsync
+		l.notifyBinLog()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	info := d.header.Info
+	return &info, nil
+}
+
+const (
+	//commit the loaded data when the batch has so many keys or bytes
+	loadBatchNum  = 4096
+	loadBatchSize = 4 * 1024 * 1024
+)
+
+type dumpLoader struct {
+	l *Ledis
+
+	//the loaded data, and the binlog events if logging
+	t *tx
+
+	num  int
+	size int
+
+	//the user keys cleared before loading, nil if not clearing
+	cleared map[string]struct{}
+
+	//called with the last key after committed
+	checkpoint func(lastKey []byte) error
+	lastKey    []byte
+}
+
+func newDumpLoader(l *Ledis, logging bool) *dumpLoader {
+	ld := new(dumpLoader)
+	ld.l = l
+	ld.t = newTx(l)
+	if !logging {
+		ld.t.binlog = nil
+	}
+	return ld
+}
+
+func (ld *dumpLoader) Put(key []byte, value []byte) error {
+	if ld.cleared != nil {
+		if err := ld.clear(key); err != nil {
 			return err
 		}
 	}
 
-	if err = wb.Flush(); err != nil {
+	ld.t.Put(key, value)
+
+	if ld.checkpoint != nil {
+		ld.lastKey = append(ld.lastKey[0:0], key...)
+	}
+
+	ld.num++
+	ld.size += len(key) + len(value)
+
+	if ld.num >= loadBatchNum || ld.size >= loadBatchSize {
+		return ld.Commit()
+	}
+	return nil
+}
+
+func (ld *dumpLoader) Commit() error {
+	if ld.num == 0 {
+		return nil
+	}
+
+	if err := ld.l.commitWithLog(ld.t.wb, ld.t.batch); err != nil {
 		return err
 	}
+	ld.t.batch = ld.t.batch[0:0]
+	ld.t.wb.Rollback()
 
-	compressBuf = nil
+	ld.num = 0
+	ld.size = 0
 
+	if ld.checkpoint != nil {
+		return ld.checkpoint(ld.lastKey)
+	}
 	return nil
 }
 
-func (l *Ledis) LoadDumpFile(path string) (*MasterInfo, error) {
-	f, err := os.Open(path)
+//clear deletes the existing data of the user key of ek the first time it is seen,
+//the deletes are committed with the loaded data before them.
+func (ld *dumpLoader) clear(ek []byte) error {
+	if len(ek) < 2 || ek[0] >= MaxDBNumber {
+		return nil
+	}
+
+	dataType, key, err := decodeDataKey(ek)
 	if err != nil {
-		return nil, err
+		//not a user key, nothing to clear
+		return nil
 	}
-	defer f.Close()
 
-	return l.LoadDump(f)
+	id := string(append([]byte{ek[0], dataType}, key...))
+	if _, ok := ld.cleared[id]; ok {
+		return nil
+	}
+	ld.cleared[id] = struct{}{}
+
+	db := ld.l.dbs[ek[0]]
+	t := ld.t
+
+	switch dataType {
+	case KVType:
+		t.Delete(db.encodeKVKey(key))
+	case HashType:
+		db.hDelete(t, key)
+	case ListType:
+		db.lDelete(t, key)
+	case ZSetType:
+		if _, err = db.zRemRange(t, key, MinScore, MaxScore, 0, -1); err != nil {
+			return err
+		}
+	case BitType:
+		db.bDelete(t, key)
+	}
+
+	_, err = db.rmExpire(t, dataType, key)
+	return err
 }
 
-func (l *Ledis) LoadDump(r io.Reader) (*MasterInfo, error) {
-	l.Lock()
-	defer l.Unlock()
+func (ld *dumpLoader) Close() {
+	ld.t.Close()
+}
 
-	info := new(MasterInfo)
+//FilterDump copies the data matched by the filter from the dump in r to a new dump in w,
+//the new dump is in format v2 with the same binlog position.
+func FilterDump(r io.Reader, w io.Writer, f *DumpFilter) (*MasterInfo, error) {
+	d, err := newDumpReader(r)
+	if err != nil {
+		return nil, err
+	}
 
-	rb := bufio.NewReaderSize(r, 4096)
+	dw := newDumpWriter(w)
+	if err = newDumpHeader(&d.header.Info).encode(dw.w); err != nil {
+		return nil, err
+	}
+
+	err = d.ForEach(func(key []byte, value []byte) error {
+		if !f.Match(key) {
+			return nil
+		}
+		return dw.Put(key, value)
+	})
 
-	err := info.ReadFrom(rb)
 	if err != nil {
 		return nil, err
 	}
 
+	if err = dw.Close(); err != nil {
+		return nil, err
+	}
+
+	info := d.header.Info
+	return &info, nil
+}
+
+type dumpReader struct {
+	rb *bufio.Reader
+
+	header dumpHeader
+}
+
+//newDumpReader reads the header of dump in format v2 or v1
+func newDumpReader(r io.Reader) (*dumpReader, error) {
+	d := new(dumpReader)
+	d.rb = bufio.NewReaderSize(r, 4096)
+
+	if magic, err := d.rb.Peek(len(dumpMagic)); err == nil && string(magic) == dumpMagic {
+		d.rb.Discard(len(dumpMagic))
+		if err = d.header.decode(d.rb); err != nil {
+			return nil, err
+		}
+	} else {
+		d.header.Version = 1
+		if err = d.header.Info.ReadFrom(d.rb); err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+//a block of dump format v2
+type dumpBlock struct {
+	data []byte
+
+	//decompressed data
+	records []byte
+	err     error
+
+	//closed after decompressed
+	done chan struct{}
+
+	//the end of dump, data is the record count
+	end bool
+}
+
+//ForEach calls fn for every record in the dump,
+//key and value are only valid in fn.
+func (d *dumpReader) ForEach(fn func(key []byte, value []byte) error) error {
+	if d.header.Version == 1 {
+		return d.forEachV1(fn)
+	}
+
+	workers := runtime.NumCPU()
+
+	//blocks in dump order, and blocks to decompress
+	blocks := make(chan *dumpBlock, 2*workers)
+	decodes := make(chan *dumpBlock, 2*workers)
+
+	quit := make(chan struct{})
+	defer close(quit)
+
+	go d.readBlocks(blocks, decodes, quit)
+
+	for i := 0; i < workers; i++ {
+		go decodeBlocks(decodes)
+	}
+
+	var num int64
+	for b := range blocks {
+		<-b.done
+
+		if b.err != nil {
+			return b.err
+		} else if b.end {
+			if int64(binary.BigEndian.Uint64(b.data)) != num {
+				return ErrDumpCorrupted
+			}
+			return nil
+		}
+
+		n, err := forEachRecord(b.records, fn)
+		if err != nil {
+			return err
+		}
+		num += n
+	}
+
+	return ErrDumpTruncated
+}
+
+//readBlocks reads the blocks until the end of dump or an error,
+//an error is sent as a block.
+func (d *dumpReader) readBlocks(blocks chan<- *dumpBlock, decodes chan<- *dumpBlock, quit <-chan struct{}) {
+	defer close(blocks)
+	defer close(decodes)
+
+	for {
+		b := d.readBlock()
+
+		//b is changed by decoding goroutine after sent
+		last := b.err != nil || b.end
+
+		if !last {
+			select {
+			case decodes <- b:
+			case <-quit:
+				return
+			}
+		} else {
+			close(b.done)
+		}
+
+		select {
+		case blocks <- b:
+		case <-quit:
+			return
+		}
+
+		if last {
+			return
+		}
+	}
+}
+
+func (d *dumpReader) readBlock() *dumpBlock {
+	b := &dumpBlock{done: make(chan struct{})}
+
+	var dataLen uint32
+	if err := binary.Read(d.rb, binary.BigEndian, &dataLen); err != nil {
+		b.err = dumpReadError(err)
+		return b
+	}
+
+	if dataLen == 0 {
+		var end [12]byte
+		if _, err := io.ReadFull(d.rb, end[:]); err != nil {
+			b.err = dumpReadError(err)
+		} else if crc32.ChecksumIEEE(end[0:8]) != binary.BigEndian.Uint32(end[8:]) {
+			b.err = ErrDumpCorrupted
+		} else {
+			b.end = true
+			b.data = end[0:8]
+		}
+		return b
+	} else if dataLen > maxDumpBlockSize {
+		b.err = ErrDumpCorrupted
+		return b
+	}
+
+	//data and crc
+	b.data = make([]byte, dataLen+4)
+	if _, err := io.ReadFull(d.rb, b.data); err != nil {
+		b.err = dumpReadError(err)
+	}
+	return b
+}
+
+func decodeBlocks(decodes <-chan *dumpBlock) {
+	for b := range decodes {
+		data := b.data[0 : len(b.data)-4]
+		crc := binary.BigEndian.Uint32(b.data[len(data):])
+
+		if crc != crc32.ChecksumIEEE(data) {
+			b.err = ErrDumpCorrupted
+		} else if records, err := snappy.Decode(nil, data); err != nil {
+			b.err = ErrDumpCorrupted
+		} else {
+			b.records = records
+		}
+
+		b.data = nil
+		close(b.done)
+	}
+}
+
+//forEachRecord calls fn for the records in the decompressed block, returns the record number
+func forEachRecord(b []byte, fn func(key []byte, value []byte) error) (int64, error) {
+	var n int64
+	for len(b) > 0 {
+		if len(b) < 2 {
+			return n, ErrDumpCorrupted
+		}
+
+		keyLen := int(binary.BigEndian.Uint16(b))
+		b = b[2:]
+		if len(b) < keyLen+4 {
+			return n, ErrDumpCorrupted
+		}
+
+		key := b[0:keyLen]
+		b = b[keyLen:]
+
+		valueLen := int(binary.BigEndian.Uint32(b))
+		b = b[4:]
+		if len(b) < valueLen {
+			return n, ErrDumpCorrupted
+		}
+
+		value := b[0:valueLen]
+		b = b[valueLen:]
+
+		if err := fn(key, value); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+func (d *dumpReader) forEachV1(fn func(key []byte, value []byte) error) error {
 	var keyLen uint16
 	var valueLen uint32
 
@@ -154,40 +854,38 @@ func (l *Ledis) LoadDump(r io.Reader) (*MasterInfo, error) {
 	deValueBuf := make([]byte, 4096)
 
 	var key, value []byte
+	var err error
 
+	rb := d.rb
 	for {
 		if err = binary.Read(rb, binary.BigEndian, &keyLen); err != nil && err != io.EOF {
-			return nil, err
+			return err
 		} else if err == io.EOF {
 			break
 		}
 
 		if _, err = io.CopyN(&keyBuf, rb, int64(keyLen)); err != nil {
-			return nil, err
+			return err
 		}
 
 		if key, err = snappy.Decode(deKeyBuf, keyBuf.Bytes()); err != nil {
-			return nil, err
+			return err
 		}
 
 		if err = binary.Read(rb, binary.BigEndian, &valueLen); err != nil {
-			return nil, err
+			return err
 		}
 
 		if _, err = io.CopyN(&valueBuf, rb, int64(valueLen)); err != nil {
-			return nil, err
+			return err
 		}
 
 		if value, err = snappy.Decode(deValueBuf, valueBuf.Bytes()); err != nil {
-			return nil, err
-		}
-
-		if err = l.ldb.Put(key, value); err != nil {
-			return nil, err
+			return err
 		}
 
-		if l.binlog != nil {
-			err = l.binlog.Log(encodeBinLogPut(key, value))
+		if err = fn(key, value); err != nil {
+			return err
 		}
 
 		keyBuf.Reset()
@@ -197,5 +895,5 @@ func (l *Ledis) LoadDump(r io.Reader) (*MasterInfo, error) {
 	deKeyBuf = nil
 	deValueBuf = nil
 
-	return info, nil
+	return nil
 }
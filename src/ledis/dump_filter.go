@@ -0,0 +1,161 @@
+package ledis
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+//DumpFilter selects the data to dump or load by the user key,
+//a nil filter matches all data.
+type DumpFilter struct {
+	//db indexes, all dbs if empty
+	DBs []int
+
+	//data types, KVType, ListType, HashType, ZSetType or BitType, all types if empty
+	Types []byte
+
+	//key pattern in path.Match syntax, like "user:*", all keys if empty
+	Pattern string
+}
+
+//NewDumpFilter creates a filter from the comma separated db indexes like "0,1",
+//the comma separated type names like "hash,zset" and the key pattern,
+//returns nil if all are empty.
+func NewDumpFilter(dbs string, types string, pattern string) (*DumpFilter, error) {
+	if len(dbs) == 0 && len(types) == 0 && len(pattern) == 0 {
+		return nil, nil
+	}
+
+	f := new(DumpFilter)
+
+	if len(dbs) > 0 {
+		for _, s := range strings.Split(dbs, ",") {
+			index, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || index < 0 || index >= int(MaxDBNumber) {
+				return nil, fmt.Errorf("invalid db index %s", s)
+			}
+			f.DBs = append(f.DBs, index)
+		}
+	}
+
+	if len(types) > 0 {
+		for _, s := range strings.Split(types, ",") {
+			dataType, ok := DataTypeByName[strings.ToLower(strings.TrimSpace(s))]
+			if !ok {
+				return nil, fmt.Errorf("invalid data type %s", s)
+			}
+			f.Types = append(f.Types, dataType)
+		}
+	}
+
+	if len(pattern) > 0 {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+		f.Pattern = pattern
+	}
+
+	return f, nil
+}
+
+//Match returns whether the stored key ek is selected,
+//the keys can not be decoded are skipped by a non-nil filter.
+func (f *DumpFilter) Match(ek []byte) bool {
+	if f == nil {
+		return true
+	}
+
+	if len(ek) < 2 {
+		return false
+	}
+
+	if len(f.DBs) > 0 && !f.matchDB(int(ek[0])) {
+		return false
+	}
+
+	if len(f.Types) == 0 && len(f.Pattern) == 0 {
+		return true
+	}
+
+	dataType, key, err := decodeDataKey(ek)
+	if err != nil {
+		return false
+	}
+
+	if len(f.Types) > 0 && !f.matchType(dataType) {
+		return false
+	}
+
+	if len(f.Pattern) > 0 {
+		ok, _ := path.Match(f.Pattern, String(key))
+		return ok
+	}
+
+	return true
+}
+
+func (f *DumpFilter) matchDB(index int) bool {
+	for _, i := range f.DBs {
+		if i == index {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *DumpFilter) matchType(dataType byte) bool {
+	for _, t := range f.Types {
+		if t == dataType {
+			return true
+		}
+	}
+	return false
+}
+
+//decodeDataKey returns the user visible data type and user key of the stored key,
+//the meta and ttl keys belong to the data type of their key.
+func decodeDataKey(ek []byte) (byte, []byte, error) {
+	db := &DB{index: ek[0]}
+
+	var key []byte
+	var err error
+
+	dataType := ek[1]
+	switch dataType {
+	case KVType:
+		key, err = db.decodeKVKey(ek)
+	case HashType:
+		key, _, err = db.hDecodeHashKey(ek)
+	case HSizeType:
+		dataType = HashType
+		key, err = db.hDecodeSizeKey(ek)
+	case ListType:
+		key, _, err = db.lDecodeListKey(ek)
+	case LMetaType:
+		dataType = ListType
+		key, err = db.lDecodeMetaKey(ek)
+	case ZSetType:
+		key, _, err = db.zDecodeSetKey(ek)
+	case ZSizeType:
+		dataType = ZSetType
+		key, err = db.zDecodeSizeKey(ek)
+	case ZScoreType:
+		dataType = ZSetType
+		key, _, _, err = db.zDecodeScoreKey(ek)
+	case BitType:
+		key, _, err = db.bDecodeBinKey(ek)
+	case BitMetaType:
+		dataType = BitType
+		key, err = db.bDecodeMetaKey(ek)
+	case ExpTimeType:
+		dataType, key, _, err = db.expDecodeTimeKey(ek)
+	case ExpMetaType:
+		dataType, key, err = db.expDecodeMetaKey(ek)
+	default:
+		err = errDataType
+	}
+
+	return dataType, key, err
+}
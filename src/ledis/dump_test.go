@@ -2,9 +2,15 @@ package ledis
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/siddontang/go-snappy/snappy"
+	"io/ioutil"
 	"os"
-	"leveldb"
+	"store"
 	"testing"
+	"time"
 )
 
 func TestDump(t *testing.T) {
@@ -59,7 +65,7 @@ func TestDump(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	it := master.ldb.RangeLimitIterator(nil, nil, leveldb.RangeClose, 0, -1)
+	it := master.ldb.RangeLimitIterator([]byte{0}, []byte{MaxDBNumber}, store.RangeROpen, 0, -1)
 	for ; it.Valid(); it.Next() {
 		key := it.Key()
 		value := it.Value()
@@ -71,3 +77,507 @@ func TestDump(t *testing.T) {
 		}
 	}
 }
+
+func newTestDumpLedis(t *testing.T) *Ledis {
+	var cfg = []byte(`
+    {
+        "data_dir" : "/tmp/test_ledis_dump_load",
+        "db" : {
+            "name" : "memory"
+        }
+    }
+    `)
+
+	l, err := OpenWithJsonConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestDumpFormat(t *testing.T) {
+	master := newTestDumpLedis(t)
+	defer master.Close()
+
+	db, _ := master.Select(0)
+	value := bytes.Repeat([]byte("v"), 100)
+	for i := 0; i < 2000; i++ {
+		db.Set([]byte(fmt.Sprintf("dump_%d", i)), value)
+	}
+
+	var buf bytes.Buffer
+	if err := master.Dump(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dump := buf.Bytes()
+	if !bytes.HasPrefix(dump, []byte(dumpMagic)) {
+		t.Fatal("no magic")
+	}
+
+	slave := newTestDumpLedis(t)
+	defer slave.Close()
+
+	if _, err := slave.LoadDump(bytes.NewReader(dump)); err != nil {
+		t.Fatal(err)
+	}
+
+	db, _ = slave.Select(0)
+	if v, err := db.Get([]byte("dump_1999")); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(v, value) {
+		t.Fatal("load dump error")
+	}
+
+	//truncated, lost the end
+	if _, err := slave.LoadDump(bytes.NewReader(dump[0 : len(dump)-10])); err != ErrDumpTruncated {
+		t.Fatal(err)
+	}
+
+	//corrupted block
+	corrupted := append([]byte{}, dump...)
+	corrupted[len(dumpMagic)+dumpHeaderSize+4+10] ^= 0xff
+	if _, err := slave.LoadDump(bytes.NewReader(corrupted)); err != ErrDumpCorrupted {
+		t.Fatal(err)
+	}
+
+	//corrupted header
+	corrupted = append([]byte{}, dump...)
+	corrupted[len(dumpMagic)+5] ^= 0xff
+	if _, err := slave.LoadDump(bytes.NewReader(corrupted)); err != ErrDumpCorrupted {
+		t.Fatal(err)
+	}
+
+	//the dump file is verified before loading, nothing is loaded if corrupted at the end,
+	//even if the data before is more than a load batch
+	mdb, _ := master.Select(0)
+	for i := 2000; i < loadBatchNum+2000; i++ {
+		mdb.Set([]byte(fmt.Sprintf("dump_%d", i)), value)
+	}
+
+	buf.Reset()
+	if err := master.Dump(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dump = buf.Bytes()
+	corrupted = append([]byte{}, dump...)
+	corrupted[len(corrupted)-40] ^= 0xff
+	name := "/tmp/test_ledis_dump_corrupted"
+	if err := ioutil.WriteFile(name, corrupted, 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(name)
+
+	if _, err := VerifyDump(bytes.NewReader(corrupted)); err != ErrDumpCorrupted {
+		t.Fatal(err)
+	} else if _, err = VerifyDump(bytes.NewReader(dump)); err != nil {
+		t.Fatal(err)
+	}
+
+	fresh := newTestDumpLedis(t)
+	defer fresh.Close()
+
+	if _, err := fresh.LoadDumpFile(name); err != ErrDumpCorrupted {
+		t.Fatal(err)
+	}
+
+	db, _ = fresh.Select(0)
+	if v, _ := db.Get([]byte("dump_0")); v != nil {
+		t.Fatal("must not load the corrupted dump")
+	}
+}
+
+func TestDumpResume(t *testing.T) {
+	master := newTestDumpLedis(t)
+	defer master.Close()
+
+	db, _ := master.Select(0)
+	for i := 0; i < loadBatchNum+100; i++ {
+		db.Set([]byte(fmt.Sprintf("resume_%05d", i)), []byte("v"))
+	}
+
+	var buf bytes.Buffer
+	if err := master.Dump(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	slave := newTestDumpLedis(t)
+	defer slave.Close()
+
+	//broken after the first batch
+	errBroken := errors.New("broken")
+	var lastKey []byte
+	_, err := slave.LoadDumpWithOptions(&buf, &LoadDumpOptions{Checkpoint: func(info *MasterInfo, key []byte) error {
+		lastKey = append([]byte{}, key...)
+		return errBroken
+	}})
+	if err != errBroken {
+		t.Fatal(err)
+	} else if v, _ := slave.ldb.Get(lastKey); v == nil {
+		t.Fatal("checkpoint key must be loaded")
+	}
+
+	//the key after checkpoint is not loaded
+	sdb, _ := slave.Select(0)
+	if v, _ := sdb.Get([]byte(fmt.Sprintf("resume_%05d", loadBatchNum))); v != nil {
+		t.Fatal(string(v))
+	}
+
+	buf.Reset()
+	if err := master.DumpAfter(&buf, lastKey, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var num int
+	if _, err := slave.LoadDumpWithOptions(&buf, &LoadDumpOptions{Checkpoint: func(info *MasterInfo, key []byte) error {
+		num++
+		return nil
+	}}); err != nil {
+		t.Fatal(err)
+	} else if num != 1 {
+		t.Fatal(num)
+	}
+
+	for i := 0; i < loadBatchNum+100; i++ {
+		if v, _ := sdb.Get([]byte(fmt.Sprintf("resume_%05d", i))); string(v) != "v" {
+			t.Fatal(i, v)
+		}
+	}
+}
+
+func TestDumpShareSnapshot(t *testing.T) {
+	l := newTestDumpLedis(t)
+	defer l.Close()
+
+	db, _ := l.Select(0)
+	db.Set([]byte("share_a"), []byte("1"))
+
+	//a running dump
+	s, err := l.acquireSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Set([]byte("share_b"), []byte("2"))
+
+	hasKey := func(key string) bool {
+		var buf bytes.Buffer
+		if err := l.Dump(&buf); err != nil {
+			t.Fatal(err)
+		}
+
+		d, err := newDumpReader(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		found := false
+		err = d.ForEach(func(k []byte, v []byte) error {
+			if bytes.Equal(k, db.encodeKVKey([]byte(key))) {
+				found = true
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return found
+	}
+
+	//the new dump uses the snapshot of the running dump
+	if !hasKey("share_a") || hasKey("share_b") {
+		t.Fatal("must use the running snapshot")
+	} else if s.ref != 1 {
+		t.Fatal(s.ref)
+	}
+
+	//the snapshot is too old to share, the running dump keeps it
+	s.created = time.Now().Add(-2 * snapshotShareTime)
+	if !hasKey("share_b") {
+		t.Fatal("must use a new snapshot")
+	} else if l.snap != nil || s.ref != 1 {
+		t.Fatal(l.snap, s.ref)
+	}
+
+	l.releaseSnapshot(s)
+
+	if l.snap != nil {
+		t.Fatal("snapshot must be closed")
+	} else if !hasKey("share_b") {
+		t.Fatal("must use a new snapshot")
+	}
+}
+
+func TestLoadDumpV1(t *testing.T) {
+	var buf bytes.Buffer
+
+	m := &MasterInfo{LogFileIndex: 1, LogPos: 100}
+	m.WriteTo(&buf)
+
+	l := newTestDumpLedis(t)
+	defer l.Close()
+
+	key, _ := snappy.Encode(nil, l.dbs[0].encodeKVKey([]byte("v1_key")))
+	binary.Write(&buf, binary.BigEndian, uint16(len(key)))
+	buf.Write(key)
+
+	value, _ := snappy.Encode(nil, []byte("v1_value"))
+	binary.Write(&buf, binary.BigEndian, uint32(len(value)))
+	buf.Write(value)
+
+	if info, err := l.LoadDump(&buf); err != nil {
+		t.Fatal(err)
+	} else if *info != *m {
+		t.Fatal(info)
+	}
+
+	db, _ := l.Select(0)
+	if v, err := db.Get([]byte("v1_key")); err != nil {
+		t.Fatal(err)
+	} else if string(v) != "v1_value" {
+		t.Fatal(string(v))
+	}
+}
+
+func TestDumpFilter(t *testing.T) {
+	master := newTestDumpLedis(t)
+	defer master.Close()
+
+	db0, _ := master.Select(0)
+	db1, _ := master.Select(1)
+
+	db0.Set([]byte("user:1"), []byte("a"))
+	db0.ZAdd([]byte("user:rank"), ScorePair{1, []byte("m")})
+	db0.Expire([]byte("user:1"), 100)
+	db0.ZAdd([]byte("other:rank"), ScorePair{1, []byte("m")})
+	db1.Set([]byte("user:2"), []byte("b"))
+
+	if _, err := NewDumpFilter("16", "", ""); err == nil {
+		t.Fatal("must error")
+	} else if _, err = NewDumpFilter("", "none", ""); err == nil {
+		t.Fatal("must error")
+	} else if f, _ := NewDumpFilter("", "", ""); f != nil {
+		t.Fatal("must nil")
+	}
+
+	load := func(dbs string, types string, pattern string) *Ledis {
+		f, err := NewDumpFilter(dbs, types, pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		if err := master.DumpWithFilter(&buf, f); err != nil {
+			t.Fatal(err)
+		}
+
+		slave := newTestDumpLedis(t)
+		if _, err := slave.LoadDump(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return slave
+	}
+
+	slave := load("0", "zset", "user:*")
+	db, _ := slave.Select(0)
+	if n, _ := db.ZCard([]byte("user:rank")); n != 1 {
+		t.Fatal(n)
+	} else if n, _ = db.ZCard([]byte("other:rank")); n != 0 {
+		t.Fatal(n)
+	} else if v, _ := db.Get([]byte("user:1")); v != nil {
+		t.Fatal("must nil")
+	} else if s, _ := db.ZScore([]byte("user:rank"), []byte("m")); s != 1 {
+		t.Fatal(s)
+	}
+	slave.Close()
+
+	//the ttl is dumped with its key
+	slave = load("0", "kv", "")
+	db, _ = slave.Select(0)
+	if v, _ := db.Get([]byte("user:1")); string(v) != "a" {
+		t.Fatal(string(v))
+	} else if ttl, _ := db.TTL([]byte("user:1")); ttl <= 0 {
+		t.Fatal(ttl)
+	} else if n, _ := db.ZCard([]byte("user:rank")); n != 0 {
+		t.Fatal(n)
+	}
+	db, _ = slave.Select(1)
+	if v, _ := db.Get([]byte("user:2")); v != nil {
+		t.Fatal("must nil")
+	}
+	slave.Close()
+
+	//filter an existing dump
+	var buf bytes.Buffer
+	master.Dump(&buf)
+
+	var filtered bytes.Buffer
+	f, _ := NewDumpFilter("1", "", "")
+	if _, err := FilterDump(&buf, &filtered, f); err != nil {
+		t.Fatal(err)
+	}
+
+	slave = newTestDumpLedis(t)
+	defer slave.Close()
+	if _, err := slave.LoadDump(&filtered); err != nil {
+		t.Fatal(err)
+	}
+
+	db, _ = slave.Select(1)
+	if v, _ := db.Get([]byte("user:2")); string(v) != "b" {
+		t.Fatal(string(v))
+	}
+	db, _ = slave.Select(0)
+	if v, _ := db.Get([]byte("user:1")); v != nil {
+		t.Fatal("must nil")
+	}
+}
+
+func TestLoadDumpFilterReplace(t *testing.T) {
+	master := newTestDumpLedis(t)
+	defer master.Close()
+
+	db, _ := master.Select(0)
+	db.HSet([]byte("h"), []byte("a"), []byte("1"))
+	db.ZAdd([]byte("z"), ScorePair{1, []byte("m")})
+	db.RPush([]byte("l"), []byte("x"))
+	db.BSetBit([]byte("b"), 1, 1)
+
+	var buf bytes.Buffer
+	if err := master.Dump(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	os.RemoveAll("/tmp/test_ledis_dump_replace")
+	slave, err := OpenWithJsonConfig([]byte(`{"data_dir" : "/tmp/test_ledis_dump_replace"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slave.Close()
+
+	//the old data of the same keys must not mix with the dump
+	sdb, _ := slave.Select(0)
+	sdb.HSet([]byte("h"), []byte("b"), []byte("2"))
+	sdb.HExpire([]byte("h"), 100)
+	sdb.ZAdd([]byte("z"), ScorePair{5, []byte("old")})
+	sdb.RPush([]byte("l"), []byte("y"), []byte("z"))
+	sdb.BSetBit([]byte("b"), 100, 1)
+	sdb.Set([]byte("keep"), []byte("1"))
+
+	f, _ := NewDumpFilter("0", "", "")
+	if _, err := slave.LoadDumpWithFilter(&buf, f); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, _ := sdb.HLen([]byte("h")); n != 1 {
+		t.Fatal(n)
+	} else if v, _ := sdb.HGet([]byte("h"), []byte("a")); string(v) != "1" {
+		t.Fatal(string(v))
+	} else if ttl, _ := sdb.HTTL([]byte("h")); ttl != -1 {
+		t.Fatal(ttl)
+	} else if n, _ := sdb.ZCard([]byte("z")); n != 1 {
+		t.Fatal(n)
+	} else if n, _ := sdb.LLen([]byte("l")); n != 1 {
+		t.Fatal(n)
+	} else if n, _ := sdb.BCount([]byte("b"), 0, -1); n != 1 {
+		t.Fatal(n)
+	} else if v, _ := sdb.Get([]byte("keep")); string(v) != "1" {
+		t.Fatal("the key not in dump must be kept")
+	}
+}
+
+func TestLoadDumpCheckpoint(t *testing.T) {
+	os.RemoveAll("/tmp/test_ledis_checkpoint")
+
+	var cfg = []byte(`
+    {
+        "data_dir" : "/tmp/test_ledis_checkpoint",
+        "binlog" : {
+            "use" : true
+        }
+    }
+    `)
+
+	l, err := OpenWithJsonConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	db, _ := l.Select(0)
+	db.Set([]byte("a"), []byte("1"))
+
+	var buf bytes.Buffer
+	if err := l.Dump(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	//a slave of l at current binlog position
+	m := &MasterInfo{l.binlog.LogFileIndex(), l.binlog.LogFilePos()}
+
+	if _, err := l.LoadDumpWithOptions(&buf, &LoadDumpOptions{BinLogCheckpoint: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	//only the new empty log file
+	if n := len(l.binlog.LogNames()); n != 1 {
+		t.Fatal(n)
+	}
+
+	var events bytes.Buffer
+	if _, err := l.ReadEventsTo(m, &events); err != nil {
+		t.Fatal(err)
+	} else if m.LogFileIndex != -1 {
+		t.Fatal("slave must fullsync", m.LogFileIndex)
+	}
+}
+
+func BenchmarkLoadDump(b *testing.B) {
+	benchmarkLoadDump(b, false)
+}
+
+func BenchmarkLoadDumpCheckpoint(b *testing.B) {
+	benchmarkLoadDump(b, true)
+}
+
+func benchmarkLoadDump(b *testing.B, checkpoint bool) {
+	os.RemoveAll("/tmp/bench_ledis_load")
+
+	var cfg = []byte(`
+    {
+        "data_dir" : "/tmp/bench_ledis_load",
+        "binlog" : {
+            "use" : true
+        }
+    }
+    `)
+
+	l, err := OpenWithJsonConfig(cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer l.Close()
+
+	db, _ := l.Select(0)
+	value := bytes.Repeat([]byte("v"), 100)
+	for i := 0; i < 100000; i++ {
+		db.Set([]byte(fmt.Sprintf("bench_%d", i)), value)
+	}
+
+	var buf bytes.Buffer
+	if err := l.Dump(&buf); err != nil {
+		b.Fatal(err)
+	}
+	dump := buf.Bytes()
+
+	opts := &LoadDumpOptions{BinLogCheckpoint: checkpoint}
+
+	b.SetBytes(int64(len(dump)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := l.LoadDumpWithOptions(bytes.NewReader(dump), opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/siddontang/go-log/log"
-	"leveldb"
+	"store"
 	"sync"
 	"time"
 )
@@ -12,7 +12,7 @@ import (
 type DB struct {
 	l *Ledis
 
-	db *leveldb.DB
+	db *store.DB
 
 	index uint8
 
@@ -28,13 +28,22 @@ type Ledis struct {
 
 	cfg *Config
 
-	ldb *leveldb.DB
+	ldb *store.DB
 	dbs [MaxDBNumber]*DB
 
 	binlog *BinLog
 
+	//closed and renewed when new binlog events are committed
+	binlogC chan struct{}
+
+	//the snapshot of running dumps, shared by the new dumps
+	snapLock sync.Mutex
+	snap     *sharedSnapshot
+
 	quit chan struct{}
 	jobs *sync.WaitGroup
+
+	expireHzC chan int
 }
 
 func OpenWithJsonConfig(configJson json.RawMessage) (*Ledis, error) {
@@ -52,7 +61,7 @@ func Open(cfg *Config) (*Ledis, error) {
 		return nil, fmt.Errorf("must set correct data_dir")
 	}
 
-	ldb, err := leveldb.Open(cfg.NewDBConfig())
+	ldb, err := store.Open(cfg.NewDBConfig())
 	if err != nil {
 		return nil, err
 	}
@@ -62,6 +71,11 @@ func Open(cfg *Config) (*Ledis, error) {
 	l.quit = make(chan struct{})
 	l.jobs = new(sync.WaitGroup)
 
+	l.binlogC = make(chan struct{})
+
+	l.cfg = cfg
+	l.expireHzC = make(chan int, 1)
+
 	l.ldb = ldb
 
 	if cfg.BinLog.Use {
@@ -70,6 +84,10 @@ func Open(cfg *Config) (*Ledis, error) {
 		if err != nil {
 			return nil, err
 		}
+
+		if err = l.recoverBinLog(); err != nil {
+			return nil, err
+		}
 	} else {
 		l.binlog = nil
 	}
@@ -80,6 +98,10 @@ func Open(cfg *Config) (*Ledis, error) {
 
 	l.activeExpireCycle()
 
+	if l.binlog != nil {
+		l.binLogSyncCycle()
+	}
+
 	return l, nil
 }
 
@@ -101,10 +123,18 @@ func newDB(l *Ledis, index uint8) *DB {
 	return d
 }
 
+func (db *DB) Index() int {
+	return int(db.index)
+}
+
 func (l *Ledis) Close() {
 	close(l.quit)
 	l.jobs.Wait()
 
+	//wait the running commit to finish
+	l.Lock()
+	defer l.Unlock()
+
 	l.ldb.Close()
 
 	if l.binlog != nil {
@@ -131,11 +161,191 @@ func (l *Ledis) FlushAll() error {
 	return nil
 }
 
+//Compact compacts all data in all dbs,
+//it may take a long time for a large data set.
+func (l *Ledis) Compact() error {
+	return l.ldb.CompactRange(nil, nil)
+}
+
+//ApproximateSize returns the approximate storage space in bytes of all dbs.
+func (l *Ledis) ApproximateSize() (int64, error) {
+	sizes, err := l.ldb.SizeOf([]store.KeyRange{{Start: []byte{0}, Limit: []byte{MaxDBNumber}}})
+	if err != nil {
+		return 0, err
+	}
+	return sizes[0], nil
+}
+
+//StoreProperty returns the property of the store driver, like "leveldb.stats".
+func (l *Ledis) StoreProperty(name string) (string, error) {
+	return l.ldb.GetProperty(name)
+}
+
+//StoreName returns the name of the store driver.
+func (l *Ledis) StoreName() string {
+	name := l.ldb.Config().Name
+	if len(name) == 0 {
+		name = store.DefaultDriverName
+	}
+	return name
+}
+
 // very dangerous to use
-func (l *Ledis) DataDB() *leveldb.DB {
+func (l *Ledis) DataDB() *store.DB {
 	return l.ldb
 }
 
+func expireInterval(hz int) time.Duration {
+	if hz <= 0 {
+		hz = DefaultExpireHz
+	} else if hz > MaxExpireHz {
+		hz = MaxExpireHz
+	}
+
+	return time.Second / time.Duration(hz)
+}
+
+// SetExpireHz changes how many times per second to retire expired keys.
+func (l *Ledis) SetExpireHz(hz int) {
+	//drop the pending one, only the last setting matters
+	select {
+	case <-l.expireHzC:
+	default:
+	}
+
+	l.expireHzC <- hz
+}
+
+// SetBinLogLimit changes max binlog file size and max binlog file num at runtime,
+// and returns the adjusted ones in effect.
+func (l *Ledis) SetBinLogLimit(maxFileSize int, maxFileNum int) (int, int, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.binlog == nil {
+		cfg := BinLogConfig{MaxFileSize: maxFileSize, MaxFileNum: maxFileNum}
+		cfg.adjust()
+		return cfg.MaxFileSize, cfg.MaxFileNum, nil
+	}
+
+	err := l.binlog.SetLimit(maxFileSize, maxFileNum)
+	return l.binlog.cfg.MaxFileSize, l.binlog.cfg.MaxFileNum, err
+}
+
+//SetBinLogRetention changes max age in seconds and max total size in bytes of binlog at runtime.
+func (l *Ledis) SetBinLogRetention(maxAge int, maxTotalSize int) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.binlog != nil {
+		l.binlog.SetRetention(maxAge, maxTotalSize)
+	}
+}
+
+//SetBinLogInUseFunc sets the function returning the smallest binlog file index still in use,
+//like by the connected slaves, the binlog files from it are not purged.
+func (l *Ledis) SetBinLogInUseFunc(f func() int64) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.binlog != nil {
+		l.binlog.SetInUseFunc(f)
+	}
+}
+
+//BinLogNotify returns a channel closed when new binlog events are committed,
+//get it before reading events, then wait it if no new event.
+func (l *Ledis) BinLogNotify() <-chan struct{} {
+	l.Lock()
+	defer l.Unlock()
+
+	return l.binlogC
+}
+
+//notifyBinLog must be called with the lock
+func (l *Ledis) notifyBinLog() {
+	close(l.binlogC)
+	l.binlogC = make(chan struct{})
+}
+
+//SetBinLogFsync changes the binlog fsync policy at runtime, always, everysec or no.
+func (l *Ledis) SetBinLogFsync(policy string) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.binlog == nil {
+		return checkFsync(policy)
+	}
+
+	return l.binlog.SetFsync(policy)
+}
+
+//BinLogStat is the status of binlog
+type BinLogStat struct {
+	LogFileIndex int64
+	LogPos       int64
+	LastEventID  uint64
+
+	Fsync      string
+	FsyncStats FsyncStats
+}
+
+//BinLogStat returns the status of binlog, or ErrBinLogNotEnabled.
+func (l *Ledis) BinLogStat() (*BinLogStat, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.binlog == nil {
+		return nil, ErrBinLogNotEnabled
+	}
+
+	st := new(BinLogStat)
+	st.LogFileIndex = l.binlog.LogFileIndex()
+	st.LogPos = l.binlog.LogFilePos()
+	st.LastEventID = l.binlog.LastEventID()
+	st.Fsync = l.binlog.Fsync()
+	st.FsyncStats = l.binlog.FsyncStats()
+	return st, nil
+}
+
+//BinLogLag returns the approximate bytes logged after the binlog position, or ErrBinLogNotEnabled.
+func (l *Ledis) BinLogLag(logFileIndex int64, logPos int64) (int64, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.binlog == nil {
+		return 0, ErrBinLogNotEnabled
+	}
+
+	return l.binlog.BytesAfter(logFileIndex, logPos), nil
+}
+
+//PurgeBinLogsTo purges the binlog files before the index, returns the number of purged files,
+//or ErrBinLogInUse if any is in use.
+func (l *Ledis) PurgeBinLogsTo(index int64) (int, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.binlog == nil {
+		return 0, ErrBinLogNotEnabled
+	}
+
+	return l.binlog.PurgeTo(index)
+}
+
+//PurgeBinLogsBefore purges the binlog files last modified before t, returns the number of purged files,
+//or ErrBinLogInUse if any is in use.
+func (l *Ledis) PurgeBinLogsBefore(t time.Time) (int, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.binlog == nil {
+		return 0, ErrBinLogNotEnabled
+	}
+
+	return l.binlog.PurgeBefore(t)
+}
+
 func (l *Ledis) activeExpireCycle() {
 	var executors []*elimination = make([]*elimination, len(l.dbs))
 	for i, db := range l.dbs {
@@ -144,7 +354,7 @@ func (l *Ledis) activeExpireCycle() {
 
 	l.jobs.Add(1)
 	go func() {
-		tick := time.NewTicker(1 * time.Second)
+		tick := time.NewTicker(expireInterval(l.cfg.ExpireHz))
 		end := false
 		done := make(chan struct{})
 		for !end {
@@ -157,6 +367,9 @@ func (l *Ledis) activeExpireCycle() {
 					done <- struct{}{}
 				}()
 				<-done
+			case hz := <-l.expireHzC:
+				tick.Stop()
+				tick = time.NewTicker(expireInterval(hz))
 			case <-l.quit:
 				end = true
 				break
@@ -167,3 +380,24 @@ func (l *Ledis) activeExpireCycle() {
 		l.jobs.Done()
 	}()
 }
+
+//binLogSyncCycle syncs binlog every second in everysec mode,
+//the sync does not block the commits.
+func (l *Ledis) binLogSyncCycle() {
+	l.jobs.Add(1)
+	go func() {
+		tick := time.NewTicker(time.Second)
+		for {
+			select {
+			case <-tick.C:
+				if err := l.binlog.SyncDirty(); err != nil {
+					log.Error("sync binlog error %s", err.Error())
+				}
+			case <-l.quit:
+				tick.Stop()
+				l.jobs.Done()
+				return
+			}
+		}
+	}()
+}
@@ -1,7 +1,7 @@
 package ledis
 
 import (
-	"leveldb"
+	"store"
 )
 
 func (db *DB) FlushAll() (drop int64, err error) {
@@ -24,6 +24,77 @@ func (db *DB) FlushAll() (drop int64, err error) {
 	return
 }
 
+//the keys of a data type are in [dataType, lastKeyType]
+var lastKeyType = map[byte]byte{
+	KVType:   KVType,
+	ListType: LMetaType,
+	HashType: HSizeType,
+	ZSetType: ZScoreType,
+	BitType:  BitMetaType,
+}
+
+//the key range [start, limit) of the data type in the db
+func (db *DB) typeRange(dataType byte) (store.KeyRange, error) {
+	last, ok := lastKeyType[dataType]
+	if !ok {
+		return store.KeyRange{}, errDataType
+	}
+
+	return store.KeyRange{
+		Start: []byte{db.index, dataType},
+		Limit: []byte{db.index, last + 1},
+	}, nil
+}
+
+//Compact compacts all data in the db to reclaim the space of deleted keys.
+func (db *DB) Compact() error {
+	return db.db.CompactRange([]byte{db.index}, []byte{db.index + 1})
+}
+
+//CompactType compacts the data of the data type in the db,
+//dataType is KVType, ListType, HashType, ZSetType or BitType.
+func (db *DB) CompactType(dataType byte) error {
+	r, err := db.typeRange(dataType)
+	if err != nil {
+		return err
+	}
+
+	return db.db.CompactRange(r.Start, r.Limit)
+}
+
+//ApproximateSize returns the approximate storage space in bytes of all data in the db,
+//the recently written data may not be counted until they are flushed to disk.
+func (db *DB) ApproximateSize() (int64, error) {
+	sizes, err := db.db.SizeOf([]store.KeyRange{{Start: []byte{db.index}, Limit: []byte{db.index + 1}}})
+	if err != nil {
+		return 0, err
+	}
+	return sizes[0], nil
+}
+
+//ApproximateTypeSizes returns the approximate storage space in bytes of each data type in the db,
+//keyed by KVType, ListType, HashType, ZSetType and BitType.
+func (db *DB) ApproximateTypeSizes() (map[byte]int64, error) {
+	types := make([]byte, 0, len(lastKeyType))
+	ranges := make([]store.KeyRange, 0, len(lastKeyType))
+	for dataType := range lastKeyType {
+		r, _ := db.typeRange(dataType)
+		types = append(types, dataType)
+		ranges = append(ranges, r)
+	}
+
+	sizes, err := db.db.SizeOf(ranges)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[byte]int64, len(types))
+	for i, dataType := range types {
+		m[dataType] = sizes[i]
+	}
+	return m, nil
+}
+
 func (db *DB) newEliminator() *elimination {
 	eliminator := newEliminator(db)
 	eliminator.regRetireContext(KVType, db.kvTx, db.delete)
@@ -36,7 +107,7 @@ func (db *DB) newEliminator() *elimination {
 }
 
 func (db *DB) flushRegion(t *tx, minKey []byte, maxKey []byte) (drop int64, err error) {
-	it := db.db.RangeIterator(minKey, maxKey, leveldb.RangeROpen)
+	it := db.db.RangeIterator(minKey, maxKey, store.RangeROpen)
 	for ; it.Valid(); it.Next() {
 		t.Delete(it.RawKey())
 		drop++
@@ -1,7 +1,10 @@
 package ledis
 
 import (
+	"fmt"
+	"math/rand"
 	"os"
+	"store"
 	"sync"
 	"testing"
 )
@@ -47,6 +50,81 @@ func TestDB(t *testing.T) {
 	getTestDB()
 }
 
+//the memory driver runs the commands of every data type too
+func TestMemoryStore(t *testing.T) {
+	os.RemoveAll("/tmp/test_ledis_memory")
+
+	l, err := OpenWithJsonConfig([]byte(`
+        {
+            "data_dir" : "/tmp/test_ledis_memory",
+            "db" : {
+                "name" : "memory"
+            }
+        }
+        `))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if name := l.StoreName(); name != "memory" {
+		t.Fatal(name)
+	}
+
+	db, _ := l.Select(0)
+	key := []byte("memory_key")
+
+	if err := db.Set(key, []byte("1")); err != nil {
+		t.Fatal(err)
+	} else if v, _ := db.Get(key); string(v) != "1" {
+		t.Fatal(string(v))
+	}
+
+	if _, err := db.HSet(key, []byte("f"), []byte("2")); err != nil {
+		t.Fatal(err)
+	} else if v, _ := db.HGet(key, []byte("f")); string(v) != "2" {
+		t.Fatal(string(v))
+	}
+
+	if _, err := db.RPush(key, []byte("a"), []byte("b")); err != nil {
+		t.Fatal(err)
+	} else if v, _ := db.LIndex(key, -1); string(v) != "b" {
+		t.Fatal(string(v))
+	}
+
+	if _, err := db.ZAdd(key, ScorePair{2, []byte("b")}, ScorePair{1, []byte("a")}); err != nil {
+		t.Fatal(err)
+	} else if v, _ := db.ZRange(key, 0, -1); len(v) != 2 || string(v[0].Member) != "a" {
+		t.Fatal(v)
+	}
+
+	if _, err := db.BSetBit(key, 10, 1); err != nil {
+		t.Fatal(err)
+	} else if n, _ := db.BCount(key, 0, -1); n != 1 {
+		t.Fatal(n)
+	}
+
+	if _, err := db.Expire(key, 100); err != nil {
+		t.Fatal(err)
+	} else if ttl, _ := db.TTL(key); ttl <= 0 {
+		t.Fatal(ttl)
+	}
+
+	if n, err := db.ApproximateSize(); err != nil {
+		t.Fatal(err)
+	} else if n == 0 {
+		t.Fatal("must not 0")
+	}
+
+	if _, err := db.FlushAll(); err != nil {
+		t.Fatal(err)
+	} else if v, _ := db.Get(key); v != nil {
+		t.Fatal("must flushed")
+	} else if n, _ := db.ZCard(key); n != 0 {
+		t.Fatal(n)
+	}
+}
+
 func TestSelect(t *testing.T) {
 	db0, _ := testLedis.Select(0)
 	db1, _ := testLedis.Select(1)
@@ -106,3 +184,114 @@ func TestFlush(t *testing.T) {
 		t.Fatal(zcnt)
 	}
 }
+
+func TestSyncMode(t *testing.T) {
+	cfg := new(Config)
+	cfg.DataDir = "/tmp/test_ledis_sync"
+	cfg.DB.BloomBits = 16
+	cfg.DB.BlockRestartInterval = 8
+	cfg.DB.ParanoidChecks = true
+	cfg.DB.Sync = true
+
+	os.RemoveAll(cfg.DataDir)
+
+	l, err := Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	//the options are passed to the store
+	sc := l.ldb.Config()
+	if sc.BloomBits != 16 || sc.BlockRestartInterval != 8 || !sc.ParanoidChecks || !sc.Sync {
+		t.Fatal(*sc)
+	}
+
+	db, _ := l.Select(0)
+
+	if err := db.Set([]byte("a"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := db.Get([]byte("a")); err != nil {
+		t.Fatal(err)
+	} else if string(v) != "1" {
+		t.Fatal(string(v))
+	}
+}
+
+func TestCompact(t *testing.T) {
+	db := getTestDB()
+
+	db.Set([]byte("compact_key"), []byte("1"))
+	db.Del([]byte("compact_key"))
+
+	if err := testLedis.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dataType := range DataTypeByName {
+		if err := db.CompactType(dataType); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := db.CompactType(ExpTimeType); err == nil {
+		t.Fatal("must error")
+	}
+}
+
+func TestApproximateSize(t *testing.T) {
+	getTestDB()
+	db, _ := testLedis.Select(int(MaxDBNumber) - 1)
+
+	//the sizes are counted by blocks, so every type has many blocks
+	value := make([]byte, 1024)
+	for i := 0; i < 200; i++ {
+		rand.Read(value)
+		db.Set([]byte(fmt.Sprintf("size_key_%d", i)), value)
+		rand.Read(value)
+		db.HSet([]byte("size_hash"), []byte(fmt.Sprintf("f_%d", i)), value)
+	}
+
+	//flush memtable so the data is counted
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	total, err := db.ApproximateSize()
+	if err != nil {
+		t.Fatal(err)
+	} else if total == 0 {
+		t.Fatal("must not 0")
+	}
+
+	sizes, err := db.ApproximateTypeSizes()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(sizes) != len(DataTypeByName) {
+		t.Fatal(len(sizes))
+	} else if sizes[KVType] == 0 || sizes[HashType] == 0 {
+		t.Fatal(sizes)
+	} else if sizes[ListType] != 0 || sizes[ZSetType] != 0 || sizes[BitType] != 0 {
+		t.Fatal(sizes)
+	} else if sizes[KVType]+sizes[HashType] > total {
+		t.Fatal(sizes, total)
+	}
+
+	if n, err := testLedis.ApproximateSize(); err != nil {
+		t.Fatal(err)
+	} else if n < total {
+		t.Fatal(n, total)
+	}
+
+	if name := testLedis.StoreName(); name != store.DefaultDriverName {
+		t.Fatal(name)
+	}
+
+	db.FlushAll()
+}
@@ -0,0 +1,334 @@
+package ledis
+
+import (
+	"bytes"
+	"fmt"
+	crdb "github.com/cupcake/rdb"
+	"github.com/siddontang/go-log/log"
+	"github.com/siddontang/rdb"
+	"io"
+	"math"
+	"store"
+	"time"
+)
+
+//import and export data in redis rdb format (version 6 and before),
+//only strings, lists, hashes and zsets are supported,
+//redis sets and ledis bits are skipped.
+//
+//zset scores are integers in ledis, a redis score with fraction can not be imported.
+
+//ImportRDB loads the keys in the rdb into the dbs with the same index,
+//the existing keys are replaced, returns the number of imported keys.
+func (l *Ledis) ImportRDB(r io.Reader) (int64, error) {
+	loader := rdb.NewLoader(r)
+	if err := loader.LoadHeader(); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	now := time.Now().Unix()
+	for {
+		e, err := loader.LoadEntry()
+		if err != nil {
+			return n, err
+		} else if e == nil {
+			break
+		}
+
+		//redis expire time is in milliseconds
+		var when int64
+		if e.ExpireAt > 0 {
+			if when = int64(e.ExpireAt / 1000); when <= now {
+				continue
+			}
+		}
+
+		if e.DB >= uint32(MaxDBNumber) {
+			return n, fmt.Errorf("key %q in redis db %d, ledis supports %d dbs only", e.Key, e.DB, MaxDBNumber)
+		}
+
+		obj, err := rdb.DecodeDump(e.ValDump)
+		if err != nil {
+			return n, err
+		}
+
+		db := l.dbs[e.DB]
+		if ok, err := db.importRDBObject(e.Key, obj, when); err != nil {
+			return n, fmt.Errorf("import key %q error %s", e.Key, err.Error())
+		} else if ok {
+			n++
+		}
+	}
+
+	if err := loader.LoadChecksum(); err != nil {
+		return n, err
+	}
+
+	return n, nil
+}
+
+//importRDBObject returns false if the object type is not supported
+func (db *DB) importRDBObject(key []byte, obj interface{}, when int64) (bool, error) {
+	var err error
+	switch v := obj.(type) {
+	case rdb.String:
+		if err = db.Set(key, v); err == nil && when > 0 {
+			_, err = db.ExpireAt(key, when)
+		}
+	case rdb.List:
+		if _, err = db.LClear(key); err != nil {
+			return false, err
+		}
+
+		if _, err = db.RPush(key, v...); err == nil && when > 0 {
+			_, err = db.LExpireAt(key, when)
+		}
+	case rdb.Hash:
+		if _, err = db.HClear(key); err != nil {
+			return false, err
+		}
+
+		args := make([]FVPair, len(v))
+		for i := range v {
+			args[i] = FVPair{Field: v[i].Field, Value: v[i].Value}
+		}
+
+		if err = db.HMset(key, args...); err == nil && when > 0 {
+			_, err = db.HExpireAt(key, when)
+		}
+	case rdb.ZSet:
+		if _, err = db.ZClear(key); err != nil {
+			return false, err
+		}
+
+		args := make([]ScorePair, len(v))
+		for i := range v {
+			score := v[i].Score
+			if score != math.Trunc(score) || score <= float64(MinScore) || score >= float64(MaxScore) {
+				return false, fmt.Errorf("member %q score %v is not a valid integer", v[i].Member, score)
+			}
+
+			args[i] = ScorePair{Score: int64(score), Member: v[i].Member}
+		}
+
+		if _, err = db.ZAdd(key, args...); err == nil && when > 0 {
+			_, err = db.ZExpireAt(key, when)
+		}
+	default:
+		log.Warn("skip key %q of unsupported rdb type %T", key, obj)
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+//ExportRDB writes a snapshot of all strings, lists, hashes and zsets in rdb format,
+//returns the number of exported keys.
+func (l *Ledis) ExportRDB(w io.Writer) (int64, error) {
+	sp, _, err := l.snapshot()
+	if err != nil {
+		return 0, err
+	}
+	defer sp.Close()
+
+	e := &rdbExporter{e: crdb.NewEncoder(&stickyWriter{w: w}), sp: sp}
+
+	if err = e.e.EncodeHeader(); err != nil {
+		return 0, err
+	}
+
+	for _, db := range l.dbs {
+		e.db = db
+		e.dbEncoded = false
+
+		for _, dataType := range []byte{KVType, ListType, HashType, ZSetType} {
+			if err = e.exportType(dataType); err != nil {
+				return e.n, err
+			}
+		}
+	}
+
+	if err = e.e.EncodeFooter(); err != nil {
+		return e.n, err
+	}
+
+	return e.n, nil
+}
+
+//stickyWriter fails all the writes after the first failure,
+//the rdb encoder ignores some write errors, they are returned by the later writes.
+type stickyWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *stickyWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	n, err := w.w.Write(p)
+	w.err = err
+	return n, err
+}
+
+type rdbExporter struct {
+	e  *crdb.Encoder
+	sp *store.Snapshot
+
+	db *DB
+
+	//select db is encoded before the first key of the db
+	dbEncoded bool
+
+	n int64
+
+	//the key exporting and its elements
+	key   []byte
+	elems [][]byte
+}
+
+//exportType exports the keys of the data type in db,
+//the elements of a key are stored together ordered by field, member or sequence.
+func (e *rdbExporter) exportType(dataType byte) error {
+	db := e.db
+
+	it := e.sp.RangeIterator([]byte{db.index, dataType}, []byte{db.index, dataType + 1}, store.RangeROpen)
+	defer it.Close()
+
+	e.key = nil
+	e.elems = e.elems[0:0]
+
+	for ; it.Valid(); it.Next() {
+		var key []byte
+		var elem []byte
+		var err error
+
+		ek := it.Key()
+		switch dataType {
+		case KVType:
+			key, err = db.decodeKVKey(ek)
+		case ListType:
+			key, _, err = db.lDecodeListKey(ek)
+		case HashType:
+			key, elem, err = db.hDecodeHashKey(ek)
+		case ZSetType:
+			key, elem, err = db.zDecodeSetKey(ek)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if e.key != nil && !bytes.Equal(key, e.key) {
+			if err = e.exportKey(dataType); err != nil {
+				return err
+			}
+		}
+
+		e.key = key
+		if elem != nil {
+			e.elems = append(e.elems, elem)
+		}
+		e.elems = append(e.elems, it.Value())
+	}
+
+	if e.key != nil {
+		return e.exportKey(dataType)
+	}
+	return nil
+}
+
+func (e *rdbExporter) exportKey(dataType byte) error {
+	key := e.key
+	elems := e.elems
+
+	e.key = nil
+	e.elems = e.elems[0:0]
+
+	when, err := Int64(e.sp.Get(e.db.expEncodeMetaKey(dataType, key)))
+	if err != nil {
+		return err
+	} else if when > 0 && when <= time.Now().Unix() {
+		//expired
+		return nil
+	}
+
+	enc := e.e
+	if !e.dbEncoded {
+		if err = enc.EncodeDatabase(int(e.db.index)); err != nil {
+			return err
+		}
+		e.dbEncoded = true
+	}
+
+	if when > 0 {
+		if err = enc.EncodeExpiry(uint64(when) * 1000); err != nil {
+			return err
+		}
+	}
+
+	switch dataType {
+	case KVType:
+		if err = e.encodeHead(crdb.TypeString, key, -1); err != nil {
+			return err
+		}
+		err = enc.EncodeString(elems[0])
+	case ListType:
+		if err = e.encodeHead(crdb.TypeList, key, len(elems)); err != nil {
+			return err
+		}
+		for _, v := range elems {
+			if err = enc.EncodeString(v); err != nil {
+				return err
+			}
+		}
+	case HashType:
+		if err = e.encodeHead(crdb.TypeHash, key, len(elems)/2); err != nil {
+			return err
+		}
+		for _, v := range elems {
+			if err = enc.EncodeString(v); err != nil {
+				return err
+			}
+		}
+	case ZSetType:
+		if err = e.encodeHead(crdb.TypeZSet, key, len(elems)/2); err != nil {
+			return err
+		}
+		for i := 0; i < len(elems); i += 2 {
+			var score int64
+			if score, err = Int64(elems[i+1], nil); err != nil {
+				return err
+			}
+
+			if err = enc.EncodeString(elems[i]); err != nil {
+				return err
+			} else if err = enc.EncodeFloat(float64(score)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	e.n++
+	return nil
+}
+
+//encodeHead encodes the value type, the key and the element count if length >= 0
+func (e *rdbExporter) encodeHead(t crdb.ValueType, key []byte, length int) error {
+	if err := e.e.EncodeType(t); err != nil {
+		return err
+	} else if err = e.e.EncodeString(key); err != nil {
+		return err
+	}
+
+	if length >= 0 {
+		return e.e.EncodeLength(uint32(length))
+	}
+	return nil
+}
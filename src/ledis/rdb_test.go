@@ -0,0 +1,149 @@
+package ledis
+
+import (
+	"bytes"
+	"errors"
+	crdb "github.com/cupcake/rdb"
+	"testing"
+	"time"
+)
+
+func TestRDB(t *testing.T) {
+	master := newTestDumpLedis(t)
+	defer master.Close()
+
+	db, _ := master.Select(2)
+	db.Set([]byte("rdb_kv"), []byte("123"))
+	db.Expire([]byte("rdb_kv"), 100)
+	db.RPush([]byte("rdb_list"), []byte("a"), []byte("b"), []byte("c"))
+	db.HMset([]byte("rdb_hash"), FVPair{[]byte("f1"), []byte("v1")}, FVPair{[]byte("f2"), []byte("v2")})
+	db.ZAdd([]byte("rdb_zset"), ScorePair{-10, []byte("m1")}, ScorePair{20, []byte("m2")})
+	db.ZExpireAt([]byte("rdb_zset"), time.Now().Unix()+100)
+
+	var buf bytes.Buffer
+	if n, err := master.ExportRDB(&buf); err != nil {
+		t.Fatal(err)
+	} else if n != 4 {
+		t.Fatal(n)
+	}
+
+	slave := newTestDumpLedis(t)
+	defer slave.Close()
+
+	if n, err := slave.ImportRDB(&buf); err != nil {
+		t.Fatal(err)
+	} else if n != 4 {
+		t.Fatal(n)
+	}
+
+	db, _ = slave.Select(2)
+	if v, _ := db.Get([]byte("rdb_kv")); string(v) != "123" {
+		t.Fatal(string(v))
+	} else if ttl, _ := db.TTL([]byte("rdb_kv")); ttl <= 0 {
+		t.Fatal(ttl)
+	}
+
+	if v, _ := db.LRange([]byte("rdb_list"), 0, -1); len(v) != 3 || string(v[2]) != "c" {
+		t.Fatal(v)
+	}
+
+	if v, _ := db.HGet([]byte("rdb_hash"), []byte("f2")); string(v) != "v2" {
+		t.Fatal(string(v))
+	}
+
+	if s, _ := db.ZScore([]byte("rdb_zset"), []byte("m1")); s != -10 {
+		t.Fatal(s)
+	} else if ttl, _ := db.ZTTL([]byte("rdb_zset")); ttl <= 0 {
+		t.Fatal(ttl)
+	}
+}
+
+//failWriter fails the nth write only
+type failWriter struct {
+	n int
+}
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	w.n--
+	if w.n == 0 {
+		return 0, errors.New("write failed")
+	}
+	return len(p), nil
+}
+
+func TestExportRDBWriteError(t *testing.T) {
+	l := newTestDumpLedis(t)
+	defer l.Close()
+
+	db, _ := l.Select(0)
+	db.Set([]byte("rdb_kv"), []byte("123"))
+	db.RPush([]byte("rdb_list"), []byte("a"), []byte("b"))
+	db.HSet([]byte("rdb_hash"), []byte("f1"), []byte("v1"))
+	db.ZAdd([]byte("rdb_zset"), ScorePair{1, []byte("m1")})
+
+	//count the writes
+	w := &failWriter{}
+	if _, err := l.ExportRDB(w); err != nil {
+		t.Fatal(err)
+	}
+
+	//every write error must be returned
+	for i := 1; i <= -w.n; i++ {
+		if _, err := l.ExportRDB(&failWriter{i}); err == nil {
+			t.Fatal("must error when write", i, "fails")
+		}
+	}
+}
+
+func TestImportRDB(t *testing.T) {
+	var buf bytes.Buffer
+
+	//an rdb made by redis, with a set and an expired key
+	e := crdb.NewEncoder(&buf)
+	e.EncodeHeader()
+	e.EncodeDatabase(1)
+	e.EncodeType(crdb.TypeSet)
+	e.EncodeString([]byte("set"))
+	e.EncodeLength(1)
+	e.EncodeString([]byte("m"))
+	e.EncodeExpiry(1000)
+	e.EncodeType(crdb.TypeString)
+	e.EncodeString([]byte("expired"))
+	e.EncodeString([]byte("1"))
+	e.EncodeType(crdb.TypeString)
+	e.EncodeString([]byte("a"))
+	e.EncodeString([]byte("1"))
+	e.EncodeFooter()
+
+	l := newTestDumpLedis(t)
+	defer l.Close()
+
+	if n, err := l.ImportRDB(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatal(n)
+	}
+
+	db, _ := l.Select(1)
+	if v, _ := db.Get([]byte("a")); string(v) != "1" {
+		t.Fatal(string(v))
+	} else if v, _ = db.Get([]byte("expired")); v != nil {
+		t.Fatal("must nil")
+	}
+
+	//float score
+	buf.Reset()
+	e = crdb.NewEncoder(&buf)
+	e.EncodeHeader()
+	e.EncodeDatabase(0)
+	e.EncodeType(crdb.TypeZSet)
+	e.EncodeString([]byte("zset"))
+	e.EncodeLength(1)
+	e.EncodeString([]byte("m"))
+	e.EncodeFloat(1.5)
+	e.EncodeFooter()
+
+	if _, err := l.ImportRDB(&buf); err == nil {
+		t.Fatal("must error")
+	}
+}
@@ -3,7 +3,6 @@ package ledis
 import (
 	"bufio"
 	"bytes"
-	"encoding/binary"
 	"errors"
 	"github.com/siddontang/go-log/log"
 	"io"
@@ -43,15 +42,12 @@ func (l *Ledis) replicatePutEvent(event []byte) error {
 		return err
 	}
 
-	if err = l.ldb.Put(key, value); err != nil {
-		return err
-	}
+	wb := l.ldb.NewWriteBatch()
+	defer wb.Close()
 
-	if l.binlog != nil {
-		err = l.binlog.Log(event)
-	}
+	wb.Put(key, value)
 
-	return err
+	return l.commitWithLog(wb, [][]byte{event})
 }
 
 func (l *Ledis) replicateDeleteEvent(event []byte) error {
@@ -60,53 +56,53 @@ func (l *Ledis) replicateDeleteEvent(event []byte) error {
 		return err
 	}
 
-	if err = l.ldb.Delete(key); err != nil {
-		return err
-	}
+	wb := l.ldb.NewWriteBatch()
+	defer wb.Close()
 
-	if l.binlog != nil {
-		err = l.binlog.Log(event)
-	}
+	wb.Delete(key)
 
-	return err
+	return l.commitWithLog(wb, [][]byte{event})
 }
 
 func (l *Ledis) replicateCommandEvent(event []byte) error {
 	return errors.New("command event not supported now")
 }
 
-func ReadEventFromReader(rb io.Reader, f func(createTime uint32, event []byte) error) error {
-	var createTime uint32
-	var dataLen uint32
-	var dataBuf bytes.Buffer
-	var err error
-
-	for {
-		if err = binary.Read(rb, binary.BigEndian, &createTime); err != nil {
-			if err == io.EOF {
-				break
-			} else {
-				return err
-			}
-		}
+//ReadBinLogEvents reads the events of a log file or sync data in format v2 or v1,
+//the event is only valid in f.
+func ReadBinLogEvents(r io.Reader, f func(e *BinLogEvent) error) error {
+	rb, ok := r.(*bufio.Reader)
+	if !ok {
+		rb = bufio.NewReaderSize(r, 4096)
+	}
 
-		if err = binary.Read(rb, binary.BigEndian, &dataLen); err != nil {
-			return err
-		}
+	h, err := readBinLogHeader(rb)
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
 
-		if _, err = io.CopyN(&dataBuf, rb, int64(dataLen)); err != nil {
+	er := newBinLogEventReader(rb, h.Version)
+	for {
+		e, err := er.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
 			return err
 		}
 
-		err = f(createTime, dataBuf.Bytes())
+		err = f(e)
 		if err != nil && err != ErrSkipEvent {
 			return err
 		}
-
-		dataBuf.Reset()
 	}
+}
 
-	return nil
+func ReadEventFromReader(rb io.Reader, f func(createTime uint32, event []byte) error) error {
+	return ReadBinLogEvents(rb, func(e *BinLogEvent) error {
+		return f(e.CreateTime, e.Payload)
+	})
 }
 
 func (l *Ledis) ReplicateFromReader(rb io.Reader) error {
@@ -151,6 +147,8 @@ func (l *Ledis) ReplicateFromBinLog(filePath string) error {
 
 const maxSyncEvents = 64
 
+//ReadEventsTo reads the events from the position in info and writes them in format v2 with header,
+//info is updated to the next position, or LogFileIndex -1 if the position is lost.
 func (l *Ledis) ReadEventsTo(info *MasterInfo, w io.Writer) (n int, err error) {
 	n = 0
 	if l.binlog == nil {
@@ -160,16 +158,19 @@ func (l *Ledis) ReadEventsTo(info *MasterInfo, w io.Writer) (n int, err error) {
 		return
 	}
 
+	//the events are logged before the data is committed, only read the committed
+	l.Lock()
+	lastIndex := l.binlog.LogFileIndex()
+	lastPos := l.binlog.LogFilePos()
+	l.Unlock()
+
 	index := info.LogFileIndex
-	offset := info.LogPos
 
 	filePath := l.binlog.FormatLogFilePath(index)
 
 	var f *os.File
 	f, err = os.Open(filePath)
 	if os.IsNotExist(err) {
-		lastIndex := l.binlog.LogFileIndex()
-
 		if index == lastIndex {
 			//no binlog at all
 			info.LogPos = 0
@@ -188,67 +189,76 @@ func (l *Ledis) ReadEventsTo(info *MasterInfo, w io.Writer) (n int, err error) {
 
 	defer f.Close()
 
-	var fileSize int64
-	st, _ := f.Stat()
-	fileSize = st.Size()
+	rb := bufio.NewReaderSize(f, 4096)
 
-	if fileSize == info.LogPos {
+	h, err := readBinLogHeader(rb)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		//the header is not written now, no event
+		h = &BinLogHeader{Version: BinLogVersion}
+		err = nil
+	} else if err != nil {
 		return
 	}
 
-	if _, err = f.Seek(offset, os.SEEK_SET); err != nil {
+	//skip header
+	if info.LogPos < h.Size() {
+		info.LogPos = h.Size()
+	}
+
+	if _, err = f.Seek(info.LogPos, os.SEEK_SET); err != nil {
 		//may be invliad seek offset
 		return
 	}
 
+	var r io.Reader = f
+	if index == lastIndex {
+		r = io.LimitReader(f, lastPos-info.LogPos)
+	}
+	rb.Reset(r)
+
+	sh := &BinLogHeader{Version: BinLogVersion, FirstEventID: h.FirstEventID}
+	if err = sh.Encode(w); err != nil {
+		return
+	}
+
 	var lastCreateTime uint32 = 0
-	var createTime uint32
-	var dataLen uint32
 
 	var eventsNum int = 0
 
+	er := newBinLogEventReader(rb, h.Version)
 	for {
-		if err = binary.Read(f, binary.BigEndian, &createTime); err != nil {
-			if err == io.EOF {
-				//we will try to use next binlog
-				if index < l.binlog.LogFileIndex() {
-					info.LogFileIndex += 1
-					info.LogPos = 0
-				}
-				err = nil
-				return
-			} else {
-				return
+		var e *BinLogEvent
+		if e, err = er.Read(); err == io.EOF {
+			//we will try to use next binlog
+			if index < lastIndex {
+				info.LogFileIndex += 1
+				info.LogPos = 0
 			}
-		}
-
-		eventsNum++
-		if lastCreateTime == 0 {
-			lastCreateTime = createTime
-		} else if lastCreateTime != createTime {
+			err = nil
 			return
-		} else if eventsNum > maxSyncEvents {
+		} else if err == io.ErrUnexpectedEOF {
+			//the event is being written, read it later
+			err = nil
 			return
-		}
-
-		if err = binary.Read(f, binary.BigEndian, &dataLen); err != nil {
+		} else if err != nil {
 			return
 		}
 
-		if err = binary.Write(w, binary.BigEndian, createTime); err != nil {
+		eventsNum++
+		if lastCreateTime == 0 {
+			lastCreateTime = e.CreateTime
+		} else if lastCreateTime != e.CreateTime {
 			return
-		}
-
-		if err = binary.Write(w, binary.BigEndian, dataLen); err != nil {
+		} else if eventsNum > maxSyncEvents {
 			return
 		}
 
-		if _, err = io.CopyN(w, f, int64(dataLen)); err != nil {
+		if err = writeBinLogEvent(w, e.CreateTime, e.ID, e.Payload); err != nil {
 			return
 		}
 
-		n += (8 + int(dataLen))
-		info.LogPos = info.LogPos + 8 + int64(dataLen)
+		n += 20 + len(e.Payload)
+		info.LogPos = info.LogPos + er.EventSize()
 	}
 
 	return
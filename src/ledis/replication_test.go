@@ -5,12 +5,12 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"store"
 	"testing"
-	"leveldb"
 )
 
 func checkLedisEqual(master *Ledis, slave *Ledis) error {
-	it := master.ldb.RangeLimitIterator(nil, nil, leveldb.RangeClose, 0, -1)
+	it := master.ldb.RangeLimitIterator([]byte{0}, []byte{MaxDBNumber}, store.RangeROpen, 0, -1)
 	for ; it.Valid(); it.Next() {
 		key := it.Key()
 		value := it.Value()
@@ -2,6 +2,7 @@ package server
 
 import (
 	"github.com/siddontang/go-log/log"
+	"sync"
 )
 
 const (
@@ -9,27 +10,67 @@ const (
 )
 
 type accessLog struct {
+	sync.Mutex
+
 	l *log.Logger
 }
 
 func newAcessLog(baseName string) (*accessLog, error) {
 	l := new(accessLog)
 
-	h, err := log.NewTimeRotatingFileHandler(baseName, log.WhenDay, 1)
-	if err != nil {
+	if err := l.Reopen(baseName); err != nil {
 		return nil, err
 	}
 
-	l.l = log.New(h, log.Ltime)
-
 	return l, nil
 }
 
+//closes current log and opens a new one, empty baseName disables access log
+func (l *accessLog) Reopen(baseName string) error {
+	var nl *log.Logger
+	if len(baseName) > 0 {
+		h, err := log.NewTimeRotatingFileHandler(baseName, log.WhenDay, 1)
+		if err != nil {
+			return err
+		}
+
+		nl = log.New(h, log.Ltime)
+	}
+
+	l.Lock()
+	if l.l != nil {
+		l.l.Close()
+	}
+	l.l = nl
+	l.Unlock()
+
+	return nil
+}
+
+func (l *accessLog) Enabled() bool {
+	l.Lock()
+	defer l.Unlock()
+
+	return l.l != nil
+}
+
 func (l *accessLog) Close() {
-	l.l.Close()
+	l.Lock()
+	defer l.Unlock()
+
+	if l.l != nil {
+		l.l.Close()
+		l.l = nil
+	}
 }
 
 func (l *accessLog) Log(remoteAddr string, usedTime int64, request []byte, err error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.l == nil {
+		return
+	}
 
 	format := `%s %q %d [%s]`
 
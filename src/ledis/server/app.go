@@ -2,10 +2,13 @@ package server
 
 import (
 	"fmt"
+	"github.com/siddontang/go-log/log"
 	"ledis"
 	"net"
-	"path"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 type App struct {
@@ -15,14 +18,41 @@ type App struct {
 
 	ldb *ledis.Ledis
 
-	closed bool
+	closeLock sync.Mutex
+	closed    bool
+
+	//guards config items which can be changed by config set
+	cfgLock sync.RWMutex
 
 	quit chan struct{}
 
+	//closed after Close finishes
+	done chan struct{}
+
 	access *accessLog
 
 	//for slave replication
 	m *master
+
+	//connected clients, keyed by client id
+	clientsLock sync.Mutex
+	clients     map[int64]*client
+	lastID      int64
+
+	//running client goroutines, waited before closing ledis
+	clientWG sync.WaitGroup
+
+	startTime time.Time
+
+	//closed and renewed when a slave acks
+	ackLock sync.Mutex
+	ackC    chan struct{}
+
+	//the running and waiting fullsyncs, fullsyncC is closed and renewed when one finishes
+	fullsyncLock    sync.Mutex
+	fullsyncRunning int
+	fullsyncWaiting int
+	fullsyncC       chan struct{}
 }
 
 func NewApp(cfg *Config) (*App, error) {
@@ -30,14 +60,24 @@ func NewApp(cfg *Config) (*App, error) {
 		return nil, fmt.Errorf("must set data_dir first")
 	}
 
+	cfg.adjust()
+
 	app := new(App)
 
 	app.quit = make(chan struct{})
+	app.done = make(chan struct{})
 
 	app.closed = false
 
 	app.cfg = cfg
 
+	app.startTime = time.Now()
+
+	app.clients = make(map[int64]*client)
+
+	app.ackC = make(chan struct{})
+	app.fullsyncC = make(chan struct{})
+
 	var err error
 
 	if strings.Contains(cfg.Addr, "/") {
@@ -50,45 +90,55 @@ func NewApp(cfg *Config) (*App, error) {
 		return nil, err
 	}
 
-	if len(cfg.AccessLog) > 0 {
-		if path.Dir(cfg.AccessLog) == "." {
-			app.access, err = newAcessLog(path.Join(cfg.DataDir, cfg.AccessLog))
-		} else {
-			app.access, err = newAcessLog(cfg.AccessLog)
-		}
-
-		if err != nil {
-			return nil, err
-		}
+	if app.access, err = newAcessLog(cfg.accessLogPath()); err != nil {
+		return nil, err
 	}
 
 	if app.ldb, err = ledis.Open(cfg.NewLedisConfig()); err != nil {
 		return nil, err
 	}
 
+	app.ldb.SetBinLogInUseFunc(app.slavesLogIndex)
+
 	app.m = newMaster(app)
 
 	return app, nil
 }
 
+// Close shutdowns the server gracefully:
+//
+// stop accepting new connections, wait in-flight commands to finish up to shutdown_timeout,
+// stop replication and save master info, then flush binlog and close the database.
 func (app *App) Close() {
+	app.closeLock.Lock()
 	if app.closed {
+		app.closeLock.Unlock()
 		return
 	}
-
 	app.closed = true
+	app.closeLock.Unlock()
 
 	close(app.quit)
 
 	app.listener.Close()
 
-	app.m.Close()
+	app.drainClients(app.cfg.shutdownTimeout())
 
-	if app.access != nil {
-		app.access.Close()
+	app.m.Lock()
+	app.m.Close()
+	if len(app.m.info.Addr) > 0 {
+		if err := app.m.saveInfo(); err != nil {
+			log.Error("save master info error %s", err.Error())
+		}
 	}
+	app.m.Unlock()
 
+	app.access.Close()
+
+	//ledis will flush and sync binlog before closing
 	app.ldb.Close()
+
+	close(app.done)
 }
 
 func (app *App) Run() {
@@ -96,10 +146,17 @@ func (app *App) Run() {
 		app.slaveof(app.cfg.SlaveOf)
 	}
 
-	for !app.closed {
+	for {
 		conn, err := app.listener.Accept()
 		if err != nil {
-			continue
+			select {
+			case <-app.quit:
+				//wait closing finished
+				<-app.done
+				return
+			default:
+				continue
+			}
 		}
 
 		newClient(conn, app)
@@ -109,3 +166,232 @@ func (app *App) Run() {
 func (app *App) Ledis() *ledis.Ledis {
 	return app.ldb
 }
+
+func (app *App) addClient(c *client) error {
+	app.clientsLock.Lock()
+	defer app.clientsLock.Unlock()
+
+	select {
+	case <-app.quit:
+		return ErrShutdown
+	default:
+	}
+
+	app.cfgLock.RLock()
+	maxClients := app.cfg.MaxClients
+	app.cfgLock.RUnlock()
+
+	if maxClients > 0 && len(app.clients) >= maxClients {
+		return ErrMaxClients
+	}
+
+	app.lastID++
+	c.id = app.lastID
+	app.clients[c.id] = c
+	app.clientWG.Add(1)
+	return nil
+}
+
+func (app *App) removeClient(c *client) {
+	app.clientsLock.Lock()
+	delete(app.clients, c.id)
+	app.clientsLock.Unlock()
+
+	app.clientWG.Done()
+}
+
+//returns connected clients ordered by id
+func (app *App) clientList() []*client {
+	app.clientsLock.Lock()
+	cs := make([]*client, 0, len(app.clients))
+	for _, c := range app.clients {
+		cs = append(cs, c)
+	}
+	app.clientsLock.Unlock()
+
+	sort.Sort(clientsByID(cs))
+	return cs
+}
+
+func (app *App) clientNum() int {
+	app.clientsLock.Lock()
+	n := len(app.clients)
+	app.clientsLock.Unlock()
+	return n
+}
+
+//slavesLogIndex returns the smallest binlog index the connected slaves are syncing, 0 if no slave
+func (app *App) slavesLogIndex() int64 {
+	var index int64
+	for _, c := range app.clientList() {
+		c.infoLock.Lock()
+		i := c.syncLogIndex
+		c.infoLock.Unlock()
+
+		if i > 0 && (index == 0 || i < index) {
+			index = i
+		}
+	}
+	return index
+}
+
+//isSlave returns true if the server is replicating from a master
+func (app *App) isSlave() bool {
+	return app.m.currentStatus().state != replStateNone
+}
+
+//slaveInfo is the status of a connected slave
+type slaveInfo struct {
+	addr string
+
+	//the binlog position the slave has applied
+	logFileIndex int64
+	logPos       int64
+
+	ackTime time.Time
+}
+
+//slaveList returns the connected slaves ordered by client id
+func (app *App) slaveList() []slaveInfo {
+	var slaves []slaveInfo
+	for _, c := range app.clientList() {
+		c.infoLock.Lock()
+		if c.syncLogIndex > 0 {
+			slaves = append(slaves, slaveInfo{c.addr, c.ackLogIndex, c.ackLogPos, c.ackTime})
+		}
+		c.infoLock.Unlock()
+	}
+	return slaves
+}
+
+func (app *App) ackNotify() <-chan struct{} {
+	app.ackLock.Lock()
+	defer app.ackLock.Unlock()
+	return app.ackC
+}
+
+func (app *App) notifyAck() {
+	app.ackLock.Lock()
+	close(app.ackC)
+	app.ackC = make(chan struct{})
+	app.ackLock.Unlock()
+}
+
+//ackedSlaves returns the number of slaves acked the binlog position
+func (app *App) ackedSlaves(logIndex int64, logPos int64) int {
+	n := 0
+	for _, c := range app.clientList() {
+		c.infoLock.Lock()
+		if c.ackLogIndex > logIndex || (c.ackLogIndex == logIndex && c.ackLogPos >= logPos) {
+			n++
+		}
+		c.infoLock.Unlock()
+	}
+	return n
+}
+
+//waitSlaves waits num slaves to ack the binlog position until timeout, 0 means no timeout,
+//returns the number of slaves acked.
+func (app *App) waitSlaves(num int, logIndex int64, logPos int64, timeout time.Duration) int {
+	var timeoutC <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
+
+	for {
+		notify := app.ackNotify()
+
+		n := app.ackedSlaves(logIndex, logPos)
+		if n >= num {
+			return n
+		}
+
+		select {
+		case <-notify:
+		case <-timeoutC:
+			return n
+		case <-app.quit:
+			return n
+		}
+	}
+}
+
+//acquireFullSync waits until the running fullsyncs are less than max_parallel_fullsyncs,
+//returns false if the server is closed.
+func (app *App) acquireFullSync() bool {
+	waiting := false
+	defer func() {
+		if waiting {
+			app.fullsyncLock.Lock()
+			app.fullsyncWaiting--
+			app.fullsyncLock.Unlock()
+		}
+	}()
+
+	for {
+		app.cfgLock.RLock()
+		max := app.cfg.maxParallelFullSyncs()
+		app.cfgLock.RUnlock()
+
+		app.fullsyncLock.Lock()
+		if app.fullsyncRunning < max {
+			app.fullsyncRunning++
+			app.fullsyncLock.Unlock()
+			return true
+		}
+
+		if !waiting {
+			waiting = true
+			app.fullsyncWaiting++
+		}
+		c := app.fullsyncC
+		app.fullsyncLock.Unlock()
+
+		select {
+		case <-c:
+		case <-app.quit:
+			return false
+		}
+	}
+}
+
+func (app *App) releaseFullSync() {
+	app.fullsyncLock.Lock()
+	app.fullsyncRunning--
+	close(app.fullsyncC)
+	app.fullsyncC = make(chan struct{})
+	app.fullsyncLock.Unlock()
+}
+
+//fullSyncNum returns the number of running and waiting fullsyncs
+func (app *App) fullSyncNum() (int, int) {
+	app.fullsyncLock.Lock()
+	defer app.fullsyncLock.Unlock()
+	return app.fullsyncRunning, app.fullsyncWaiting
+}
+
+func (app *App) drainClients(timeout time.Duration) {
+	//stop reading new requests, a running command can still reply
+	for _, c := range app.clientList() {
+		c.closeRead()
+	}
+
+	deadline := time.Now().Add(timeout)
+	for app.clientNum() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	//force closing clients which can not finish in time
+	for _, c := range app.clientList() {
+		c.infoLock.Lock()
+		log.Warn("close client %s forcibly, last command %s", c.addr, c.lastCmd)
+		c.infoLock.Unlock()
+
+		c.c.Close()
+	}
+
+	//a forcibly closed client may be still in a command, wait it returns
+	app.clientWG.Wait()
+}
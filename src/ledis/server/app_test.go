@@ -5,6 +5,7 @@ import (
 	"os"
 	"sync"
 	"testing"
+	"time"
 )
 
 var testAppOnce sync.Once
@@ -65,3 +66,188 @@ func startTestApp() {
 func TestApp(t *testing.T) {
 	startTestApp()
 }
+
+func TestShutdown(t *testing.T) {
+	cfg := new(Config)
+	cfg.DataDir = "/tmp/test_shutdown"
+	cfg.Addr = "127.0.0.1:11186"
+	cfg.BinLog.Use = true
+
+	os.RemoveAll(cfg.DataDir)
+
+	app, err := NewApp(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		app.Run()
+		close(done)
+	}()
+
+	clientCfg := new(ledis_client.Config)
+	clientCfg.Addr = cfg.Addr
+	clientCfg.MaxIdleConns = 1
+	c := ledis_client.NewClient(clientCfg)
+	defer c.Close()
+
+	if _, err := c.Do("set", "a", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := ledis_client.String(c.Do("shutdown")); err != nil {
+		t.Fatal(err)
+	} else if ok != OK {
+		t.Fatal(ok)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown timeout")
+	}
+
+	if _, err := c.Do("get", "a"); err == nil {
+		t.Fatal("must error")
+	}
+
+	//reopen, data must be kept
+	app, err = NewApp(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Close()
+
+	db, _ := app.ldb.Select(0)
+	if v, err := db.Get([]byte("a")); err != nil {
+		t.Fatal(err)
+	} else if string(v) != "1" {
+		t.Fatal(string(v))
+	}
+}
+
+//test_block blocks until testBlockC is closed, then writes a key
+var testBlockStarted = make(chan struct{})
+var testBlockC = make(chan struct{})
+var testBlockErr error
+
+func testBlockCommand(c *client) error {
+	close(testBlockStarted)
+	<-testBlockC
+
+	testBlockErr = c.db.Set([]byte("block_a"), []byte("1"))
+	c.writeStatus(OK)
+	return nil
+}
+
+func init() {
+	register("test_block", testBlockCommand, cmdWrite)
+}
+
+func TestShutdownWaitCommand(t *testing.T) {
+	cfg := new(Config)
+	cfg.DataDir = "/tmp/test_shutdown_wait"
+	cfg.Addr = "127.0.0.1:11187"
+	cfg.ShutdownTimeout = 1
+
+	os.RemoveAll(cfg.DataDir)
+
+	app, err := NewApp(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Run()
+
+	clientCfg := new(ledis_client.Config)
+	clientCfg.Addr = cfg.Addr
+	c := ledis_client.NewClient(clientCfg)
+	defer c.Close()
+
+	go c.Do("test_block")
+	<-testBlockStarted
+
+	closed := make(chan struct{})
+	go func() {
+		app.Close()
+		close(closed)
+	}()
+
+	//the client is closed forcibly after the timeout, but ledis is kept until the command returns
+	select {
+	case <-closed:
+		t.Fatal("closed before the command returns")
+	case <-time.After(2 * time.Second):
+	}
+
+	close(testBlockC)
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown timeout")
+	}
+
+	if testBlockErr != nil {
+		t.Fatal(testBlockErr)
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	c := getTestConn()
+	defer c.Close()
+
+	if err := c.Send("set", "pipeline_a", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Send("incr", "pipeline_a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Send("get", "pipeline_a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := ledis_client.String(c.Receive()); err != nil {
+		t.Fatal(err)
+	} else if ok != OK {
+		t.Fatal(ok)
+	}
+
+	if n, err := ledis_client.Int(c.Receive()); err != nil {
+		t.Fatal(err)
+	} else if n != 2 {
+		t.Fatal(n)
+	}
+
+	if v, err := ledis_client.String(c.Receive()); err != nil {
+		t.Fatal(err)
+	} else if v != "2" {
+		t.Fatal(v)
+	}
+
+	if _, err := c.Receive(); err == nil {
+		t.Fatal("must error, no pending reply")
+	}
+
+	c.Send("incr", "pipeline_a")
+	c.Send("incr", "pipeline_a")
+	if replies, err := ledis_client.Values(c.Do("")); err != nil {
+		t.Fatal(err)
+	} else if len(replies) != 2 || replies[1].(int64) != 4 {
+		t.Fatal(replies)
+	}
+
+	//pending error reply is returned by Do
+	c.Send("incr", "pipeline_a", "bad_arg")
+	if v, err := ledis_client.String(c.Do("get", "pipeline_a")); err == nil {
+		t.Fatal("must error")
+	} else if v != "" {
+		t.Fatal(v)
+	}
+}
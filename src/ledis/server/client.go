@@ -11,6 +11,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -26,6 +27,9 @@ type client struct {
 	rb *bufio.Reader
 	wb *bufio.Writer
 
+	//counts the bytes written to connection by wb
+	cw countWriter
+
 	cmd  string
 	args [][]byte
 
@@ -36,6 +40,25 @@ type client struct {
 	compressBuf []byte
 
 	logBuf bytes.Buffer
+
+	//below are client infos for client list, guarded by infoLock
+	infoLock sync.Mutex
+
+	id   int64
+	addr string
+	name string
+
+	createTime time.Time
+	lastTime   time.Time
+	lastCmd    string
+
+	//the binlog index a slave client is syncing, 0 if not a slave
+	syncLogIndex int64
+
+	//the binlog position applied by a slave, acked by psync or requested by sync
+	ackLogIndex int64
+	ackLogPos   int64
+	ackTime     time.Time
 }
 
 func newClient(c net.Conn, app *App) {
@@ -48,12 +71,24 @@ func newClient(c net.Conn, app *App) {
 	co.c = c
 
 	co.rb = bufio.NewReaderSize(c, 256)
-	co.wb = bufio.NewWriterSize(c, 256)
+	co.cw.w = c
+	co.wb = bufio.NewWriterSize(&co.cw, 256)
 
 	co.reqC = make(chan error, 1)
 
 	co.compressBuf = make([]byte, 256)
 
+	co.addr = c.RemoteAddr().String()
+	co.createTime = time.Now()
+	co.lastTime = co.createTime
+
+	if err := app.addClient(co); err != nil {
+		co.writeError(err)
+		co.wb.Flush()
+		c.Close()
+		return
+	}
+
 	go co.run()
 }
 
@@ -68,9 +103,15 @@ func (c *client) run() {
 		}
 
 		c.c.Close()
+
+		c.app.removeClient(c)
 	}()
 
 	for {
+		if timeout := c.app.cfg.IdleTimeout; timeout > 0 {
+			c.c.SetReadDeadline(time.Now().Add(time.Duration(timeout) * time.Second))
+		}
+
 		req, err := c.readRequest()
 		if err != nil {
 			return
@@ -80,6 +121,19 @@ func (c *client) run() {
 	}
 }
 
+type closeReader interface {
+	CloseRead() error
+}
+
+//closes the read side of connection, the running command can still write reply
+func (c *client) closeRead() {
+	if cr, ok := c.c.(closeReader); ok {
+		cr.CloseRead()
+	} else {
+		c.c.Close()
+	}
+}
+
 func (c *client) readLine() ([]byte, error) {
 	return ReadLine(c.rb)
 }
@@ -150,20 +204,55 @@ func (c *client) handleRequest(req [][]byte) {
 		c.cmd = strings.ToLower(ledis.String(req[0]))
 		c.args = req[1:]
 
-		f, ok := regCmds[c.cmd]
+		c.infoLock.Lock()
+		c.lastTime = start
+		c.lastCmd = c.cmd
+		c.infoLock.Unlock()
+
+		cmd, ok := regCmds[c.cmd]
 		if !ok {
 			err = ErrNotFound
 		} else {
-			go func() {
-				c.reqC <- f(c)
-			}()
-			err = <-c.reqC
+			c.app.cfgLock.RLock()
+			readOnly := *c.app.cfg.SlaveReadOnly
+			minSlaves := c.app.cfg.MinSlavesToAck
+			ackTimeout := c.app.cfg.minSlavesAckTimeout()
+			c.app.cfgLock.RUnlock()
+
+			write := cmd.flag&cmdWrite != 0
+
+			if write && readOnly && c.app.isSlave() {
+				err = ErrReadOnly
+			} else {
+				var before *ledis.BinLogStat
+				if write && minSlaves > 0 {
+					before, _ = c.ldb.BinLogStat()
+				}
+
+				sent := c.cw.n
+
+				go func() {
+					c.reqC <- cmd.f(c)
+				}()
+				err = <-c.reqC
+
+				if err == nil && before != nil {
+					err = c.waitSlavesAck(before, minSlaves, ackTimeout, sent)
+				}
+			}
 		}
 	}
 
 	duration := time.Since(start)
 
-	if c.app.access != nil {
+	c.app.cfgLock.RLock()
+	slowThreshold := time.Duration(c.app.cfg.SlowLogThreshold) * time.Millisecond
+	c.app.cfgLock.RUnlock()
+
+	slow := slowThreshold > 0 && duration >= slowThreshold
+	access := c.app.access.Enabled()
+
+	if slow || access {
 		c.logBuf.Reset()
 		for i, r := range req {
 			left := 256 - c.logBuf.Len()
@@ -178,8 +267,14 @@ func (c *client) handleRequest(req [][]byte) {
 				c.logBuf.Write(r[0:left])
 			}
 		}
+	}
+
+	if slow {
+		log.Warn("slow command %s %q used %d ms", c.addr, c.logBuf.Bytes(), duration.Nanoseconds()/1000000)
+	}
 
-		c.app.access.Log(c.c.RemoteAddr().String(), duration.Nanoseconds()/1000000, c.logBuf.Bytes(), err)
+	if access {
+		c.app.access.Log(c.addr, duration.Nanoseconds()/1000000, c.logBuf.Bytes(), err)
 	}
 
 	if err != nil {
@@ -189,7 +284,49 @@ func (c *client) handleRequest(req [][]byte) {
 	c.wb.Flush()
 }
 
+//waitSlavesAck waits num slaves to ack the binlog position if the write command has logged events,
+//the reply of the command is replaced by an error if timeout.
+func (c *client) waitSlavesAck(before *ledis.BinLogStat, num int, timeout time.Duration, sent int64) error {
+	st, err := c.ldb.BinLogStat()
+	if err != nil || st.LastEventID == before.LastEventID {
+		//nothing changed
+		return nil
+	}
+
+	if c.app.waitSlaves(num, st.LogFileIndex, st.LogPos, timeout) >= num {
+		return nil
+	}
+
+	if c.cw.n == sent {
+		//drop the reply not sent
+		c.wb.Reset(&c.cw)
+	} else {
+		//the reply is partially sent, close the connection to not confirm the write
+		c.c.Close()
+	}
+
+	return ErrNoSlavesAck
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
 func (c *client) writeError(err error) {
+	if err == ErrReadOnly {
+		c.wb.WriteByte('-')
+		c.wb.Write(ledis.Slice(err.Error()))
+		c.wb.Write(Delims)
+		return
+	}
+
 	c.wb.Write(ledis.Slice("-ERR"))
 	if err != nil {
 		c.wb.WriteByte(' ')
@@ -304,12 +441,3 @@ func (c *client) writeScorePairArray(ay []ledis.ScorePair, withScores bool) {
 		}
 	}
 }
-
-func (c *client) writeBulkFrom(n int64, rb io.Reader) {
-	c.wb.WriteByte('$')
-	c.wb.Write(ledis.Slice(strconv.FormatInt(n, 10)))
-	c.wb.Write(Delims)
-
-	io.Copy(c.wb, rb)
-	c.wb.Write(Delims)
-}
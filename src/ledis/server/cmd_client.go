@@ -0,0 +1,132 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"ledis"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type clientsByID []*client
+
+func (s clientsByID) Len() int           { return len(s) }
+func (s clientsByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s clientsByID) Less(i, j int) bool { return s[i].id < s[j].id }
+
+//format: id=1 addr=127.0.0.1:1234 name= db=0 age=10 idle=0 cmd=client
+func (c *client) info(now time.Time) string {
+	c.infoLock.Lock()
+	defer c.infoLock.Unlock()
+
+	return fmt.Sprintf("id=%d addr=%s name=%s db=%d age=%d idle=%d cmd=%s",
+		c.id, c.addr, c.name, c.db.Index(),
+		int64(now.Sub(c.createTime).Seconds()),
+		int64(now.Sub(c.lastTime).Seconds()),
+		c.lastCmd)
+}
+
+func clientListCommand(c *client) error {
+	if len(c.args) != 1 {
+		return ErrCmdParams
+	}
+
+	var buf bytes.Buffer
+	now := time.Now()
+	for _, co := range c.app.clientList() {
+		buf.WriteString(co.info(now))
+		buf.WriteByte('\n')
+	}
+
+	c.writeBulk(buf.Bytes())
+	return nil
+}
+
+//client kill addr
+//client kill id
+func clientKillCommand(c *client) error {
+	if len(c.args) != 2 {
+		return ErrCmdParams
+	}
+
+	target := ledis.String(c.args[1])
+	id, err := strconv.ParseInt(target, 10, 64)
+	if err != nil {
+		id = 0
+	}
+
+	var n int64 = 0
+	for _, co := range c.app.clientList() {
+		if co.addr == target || co.id == id {
+			//the client goroutine will exit and unregister itself
+			co.c.Close()
+			n++
+		}
+	}
+
+	if n == 0 {
+		return fmt.Errorf("no such client %s", target)
+	}
+
+	c.writeStatus(OK)
+	return nil
+}
+
+func clientSetNameCommand(c *client) error {
+	if len(c.args) != 2 {
+		return ErrCmdParams
+	}
+
+	name := ledis.String(c.args[1])
+	if strings.ContainsAny(name, " \r\n") {
+		return fmt.Errorf("client names cannot contain spaces or newlines")
+	}
+
+	c.infoLock.Lock()
+	c.name = name
+	c.infoLock.Unlock()
+
+	c.writeStatus(OK)
+	return nil
+}
+
+func clientGetNameCommand(c *client) error {
+	if len(c.args) != 1 {
+		return ErrCmdParams
+	}
+
+	c.infoLock.Lock()
+	name := c.name
+	c.infoLock.Unlock()
+
+	if len(name) == 0 {
+		c.writeBulk(nil)
+	} else {
+		c.writeBulk(ledis.Slice(name))
+	}
+	return nil
+}
+
+func clientCommand(c *client) error {
+	if len(c.args) == 0 {
+		return ErrCmdParams
+	}
+
+	switch strings.ToLower(ledis.String(c.args[0])) {
+	case "list":
+		return clientListCommand(c)
+	case "kill":
+		return clientKillCommand(c)
+	case "setname":
+		return clientSetNameCommand(c)
+	case "getname":
+		return clientGetNameCommand(c)
+	default:
+		return ErrCmdParams
+	}
+}
+
+func init() {
+	register("client", clientCommand, cmdRead)
+}
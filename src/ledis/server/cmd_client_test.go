@@ -0,0 +1,81 @@
+package server
+
+import (
+	ledis_client "ledis/client"
+	"strings"
+	"testing"
+)
+
+func TestClientCommand(t *testing.T) {
+	c := getTestConn()
+	defer c.Close()
+
+	if v, err := c.Do("client", "getname"); err != nil {
+		t.Fatal(err)
+	} else if v != nil {
+		t.Fatal(v)
+	}
+
+	if ok, err := ledis_client.String(c.Do("client", "setname", "test_client")); err != nil {
+		t.Fatal(err)
+	} else if ok != OK {
+		t.Fatal(ok)
+	}
+
+	if name, err := ledis_client.String(c.Do("client", "getname")); err != nil {
+		t.Fatal(err)
+	} else if name != "test_client" {
+		t.Fatal(name)
+	}
+
+	if _, err := c.Do("client", "setname", "bad name"); err == nil {
+		t.Fatal("must error")
+	}
+
+	//use another client pool, because the connection will be killed later
+	cfg := new(ledis_client.Config)
+	cfg.Addr = "127.0.0.1:16380"
+	cfg.MaxIdleConns = 1
+	killClient := ledis_client.NewClient(cfg)
+	defer killClient.Close()
+
+	c2 := killClient.Get()
+	defer c2.Close()
+
+	if _, err := c2.Do("select", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := ledis_client.String(c.Do("client", "list"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var killAddr string
+	for _, line := range strings.Split(strings.TrimSpace(list), "\n") {
+		if strings.Contains(line, "name=test_client") {
+			if !strings.Contains(line, "cmd=client") {
+				t.Fatal(line)
+			}
+		} else if strings.Contains(line, "cmd=select") {
+			if !strings.Contains(line, "db=1") {
+				t.Fatal(line)
+			}
+			killAddr = strings.Fields(line)[1][len("addr="):]
+		}
+	}
+
+	if len(killAddr) == 0 {
+		t.Fatal(list)
+	}
+
+	if ok, err := ledis_client.String(c.Do("client", "kill", killAddr)); err != nil {
+		t.Fatal(err)
+	} else if ok != OK {
+		t.Fatal(ok)
+	}
+
+	if _, err := c.Do("client", "kill", killAddr); err == nil {
+		t.Fatal("must error")
+	}
+}
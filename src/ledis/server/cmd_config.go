@@ -0,0 +1,262 @@
+package server
+
+import (
+	"fmt"
+	"ledis"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//config items which can be changed at runtime, and the function to apply the change
+var configSetters = map[string]func(app *App) error{
+	"access_log":             (*App).applyAccessLog,
+	"slowlog_threshold":      nil,
+	"binlog.max_file_size":   (*App).applyBinLogLimit,
+	"binlog.max_file_num":    (*App).applyBinLogLimit,
+	"binlog.max_age":         (*App).applyBinLogRetention,
+	"binlog.max_total_size":  (*App).applyBinLogRetention,
+	"binlog.fsync":           (*App).applyBinLogFsync,
+	"maxclients":             nil,
+	"min_slaves_to_ack":      nil,
+	"min_slaves_ack_timeout": nil,
+	"max_parallel_fullsyncs": nil,
+	"slave_read_only":        nil,
+	"expire_hz":              (*App).applyExpireHz,
+}
+
+//flattens config fields by json name, nested field is named like db.cache_size
+func configFields(v reflect.Value, prefix string, fields map[string]reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "-" || len(name) == 0 {
+			continue
+		}
+
+		if f := v.Field(i); f.Kind() == reflect.Struct {
+			configFields(f, prefix+name+".", fields)
+		} else {
+			fields[prefix+name] = f
+		}
+	}
+}
+
+func (app *App) configFields() map[string]reflect.Value {
+	fields := make(map[string]reflect.Value)
+	configFields(reflect.ValueOf(app.cfg).Elem(), "", fields)
+	return fields
+}
+
+func formatConfigValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Ptr:
+		if v.IsNil() {
+			return ""
+		}
+		return formatConfigValue(v.Elem())
+	default:
+		return v.String()
+	}
+}
+
+func setConfigValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Ptr:
+		//a new value, the old one may be still in use
+		p := reflect.New(v.Type().Elem())
+		if err := setConfigValue(p.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(p)
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Kind())
+	}
+
+	return nil
+}
+
+func (app *App) applyAccessLog() error {
+	app.cfgLock.RLock()
+	name := app.cfg.accessLogPath()
+	app.cfgLock.RUnlock()
+
+	return app.access.Reopen(name)
+}
+
+func (app *App) applyBinLogLimit() error {
+	app.cfgLock.RLock()
+	maxFileSize := app.cfg.BinLog.MaxFileSize
+	maxFileNum := app.cfg.BinLog.MaxFileNum
+	app.cfgLock.RUnlock()
+
+	maxFileSize, maxFileNum, err := app.ldb.SetBinLogLimit(maxFileSize, maxFileNum)
+	if err != nil {
+		return err
+	}
+
+	//keep the adjusted limits, CONFIG GET and REWRITE report what is in effect
+	app.cfgLock.Lock()
+	app.cfg.BinLog.MaxFileSize = maxFileSize
+	app.cfg.BinLog.MaxFileNum = maxFileNum
+	app.cfgLock.Unlock()
+
+	return nil
+}
+
+func (app *App) applyBinLogRetention() error {
+	app.cfgLock.RLock()
+	maxAge := app.cfg.BinLog.MaxAge
+	maxTotalSize := app.cfg.BinLog.MaxTotalSize
+	app.cfgLock.RUnlock()
+
+	app.ldb.SetBinLogRetention(maxAge, maxTotalSize)
+	return nil
+}
+
+func (app *App) applyBinLogFsync() error {
+	app.cfgLock.RLock()
+	policy := app.cfg.BinLog.Fsync
+	app.cfgLock.RUnlock()
+
+	return app.ldb.SetBinLogFsync(policy)
+}
+
+func (app *App) applyExpireHz() error {
+	app.cfgLock.RLock()
+	hz := app.cfg.ExpireHz
+	app.cfgLock.RUnlock()
+
+	app.ldb.SetExpireHz(hz)
+	return nil
+}
+
+func configGetCommand(c *client) error {
+	if len(c.args) != 2 {
+		return ErrCmdParams
+	}
+
+	pattern := strings.ToLower(ledis.String(c.args[1]))
+
+	c.app.cfgLock.RLock()
+	defer c.app.cfgLock.RUnlock()
+
+	fields := c.app.configFields()
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		if ok, err := path.Match(pattern, name); err != nil {
+			return err
+		} else if ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	ay := make([][]byte, 0, 2*len(names))
+	for _, name := range names {
+		ay = append(ay, []byte(name), []byte(formatConfigValue(fields[name])))
+	}
+
+	c.writeSliceArray(ay)
+	return nil
+}
+
+func configSetCommand(c *client) error {
+	if len(c.args) != 3 {
+		return ErrCmdParams
+	}
+
+	name := strings.ToLower(ledis.String(c.args[1]))
+	value := string(c.args[2])
+
+	apply, ok := configSetters[name]
+	if !ok {
+		return fmt.Errorf("unsupported config set %s", name)
+	}
+
+	c.app.cfgLock.Lock()
+	field := c.app.configFields()[name]
+	//keep the old value itself, a nil pointer can not be parsed back from its format
+	old := reflect.New(field.Type()).Elem()
+	old.Set(field)
+	err := setConfigValue(field, value)
+	c.app.cfgLock.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if apply != nil {
+		if err = apply(c.app); err != nil {
+			//restore the old one
+			c.app.cfgLock.Lock()
+			field.Set(old)
+			c.app.cfgLock.Unlock()
+
+			return err
+		}
+	}
+
+	c.writeStatus(OK)
+	return nil
+}
+
+func configRewriteCommand(c *client) error {
+	if len(c.args) != 1 {
+		return ErrCmdParams
+	}
+
+	c.app.cfgLock.RLock()
+	err := c.app.cfg.Rewrite()
+	c.app.cfgLock.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	c.writeStatus(OK)
+	return nil
+}
+
+func configCommand(c *client) error {
+	if len(c.args) == 0 {
+		return ErrCmdParams
+	}
+
+	switch strings.ToLower(ledis.String(c.args[0])) {
+	case "get":
+		return configGetCommand(c)
+	case "set":
+		return configSetCommand(c)
+	case "rewrite":
+		return configRewriteCommand(c)
+	default:
+		return ErrCmdParams
+	}
+}
+
+func init() {
+	register("config", configCommand, cmdRead)
+}
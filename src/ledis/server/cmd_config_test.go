@@ -0,0 +1,130 @@
+package server
+
+import (
+	"ledis"
+	ledis_client "ledis/client"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestConfigCommand(t *testing.T) {
+	c := getTestConn()
+	defer c.Close()
+
+	if v, err := ledis_client.Strings(c.Do("config", "get", "db.block_*")); err != nil {
+		t.Fatal(err)
+	} else if len(v) != 4 {
+		t.Fatal(v)
+	} else if v[2] != "db.block_size" || v[3] != "32768" {
+		t.Fatal(v)
+	}
+
+	if ok, err := ledis_client.String(c.Do("config", "set", "maxclients", 100)); err != nil {
+		t.Fatal(err)
+	} else if ok != OK {
+		t.Fatal(ok)
+	}
+
+	if v, err := ledis_client.Strings(c.Do("config", "get", "maxclients")); err != nil {
+		t.Fatal(err)
+	} else if len(v) != 2 || v[1] != "100" {
+		t.Fatal(v)
+	}
+
+	if _, err := c.Do("config", "set", "maxclients", "abc"); err == nil {
+		t.Fatal("must error")
+	}
+
+	if _, err := c.Do("config", "set", "data_dir", "/tmp"); err == nil {
+		t.Fatal("must error")
+	}
+
+	if ok, err := ledis_client.String(c.Do("config", "set", "expire_hz", 10)); err != nil {
+		t.Fatal(err)
+	} else if ok != OK {
+		t.Fatal(ok)
+	}
+
+	if _, err := c.Do("config", "set", "binlog.fsync", "sometimes"); err == nil {
+		t.Fatal("must error")
+	}
+
+	if ok, err := ledis_client.String(c.Do("config", "set", "binlog.fsync", "always")); err != nil {
+		t.Fatal(err)
+	} else if ok != OK {
+		t.Fatal(ok)
+	}
+
+	//the limit is adjusted to the max one in effect
+	if ok, err := ledis_client.String(c.Do("config", "set", "binlog.max_file_num", ledis.MaxBinLogFileNum+1)); err != nil {
+		t.Fatal(err)
+	} else if ok != OK {
+		t.Fatal(ok)
+	}
+
+	if v, err := ledis_client.Strings(c.Do("config", "get", "binlog.max_file_num")); err != nil {
+		t.Fatal(err)
+	} else if len(v) != 2 || v[1] != strconv.Itoa(ledis.MaxBinLogFileNum) {
+		t.Fatal(v)
+	}
+
+	if _, err := c.Do("config", "rewrite"); err == nil {
+		t.Fatal("must error, no config file")
+	}
+
+	fileName := "/tmp/testdb/ledis_config_test.json"
+	os.Remove(fileName)
+
+	testApp.cfgLock.Lock()
+	testApp.cfg.FileName = fileName
+	testApp.cfgLock.Unlock()
+
+	defer func() {
+		testApp.cfgLock.Lock()
+		testApp.cfg.FileName = ""
+		testApp.cfg.MaxClients = 0
+		testApp.cfgLock.Unlock()
+	}()
+
+	if ok, err := ledis_client.String(c.Do("config", "rewrite")); err != nil {
+		t.Fatal(err)
+	} else if ok != OK {
+		t.Fatal(ok)
+	}
+
+	if cfg, err := NewConfigWithFile(fileName); err != nil {
+		t.Fatal(err)
+	} else if cfg.MaxClients != 100 || cfg.ExpireHz != 10 || cfg.DataDir != "/tmp/testdb" {
+		t.Fatal(cfg)
+	} else if cfg.BinLog.MaxFileNum != ledis.MaxBinLogFileNum {
+		t.Fatal(cfg)
+	}
+}
+
+func TestNewConfig(t *testing.T) {
+	if cfg, err := NewConfig([]byte(`{"data_dir": "/tmp/ledis_test"}`)); err != nil {
+		t.Fatal(err)
+	} else if cfg.adjust(); !*cfg.SlaveReadOnly {
+		t.Fatal("slave must be read only by default")
+	}
+
+	if cfg, err := NewConfig([]byte(`{"slave_read_only": false}`)); err != nil {
+		t.Fatal(err)
+	} else if cfg.adjust(); *cfg.SlaveReadOnly {
+		t.Fatal("slave must be writable")
+	}
+
+	//the config built directly has the defaults too
+	cfg := new(Config)
+	if cfg.adjust(); !*cfg.SlaveReadOnly {
+		t.Fatal("slave must be read only by default")
+	}
+
+	//the store driver is copied to ledis config
+	if cfg, err := NewConfig([]byte(`{"db": {"name": "memory"}}`)); err != nil {
+		t.Fatal(err)
+	} else if name := cfg.NewLedisConfig().DB.Name; name != "memory" {
+		t.Fatal(name)
+	}
+}
@@ -293,24 +293,24 @@ func hpersistCommand(c *client) error {
 }
 
 func init() {
-	register("hdel", hdelCommand)
-	register("hexists", hexistsCommand)
-	register("hget", hgetCommand)
-	register("hgetall", hgetallCommand)
-	register("hincrby", hincrbyCommand)
-	register("hkeys", hkeysCommand)
-	register("hlen", hlenCommand)
-	register("hmget", hmgetCommand)
-	register("hmset", hmsetCommand)
-	register("hset", hsetCommand)
-	register("hvals", hvalsCommand)
+	register("hdel", hdelCommand, cmdWrite)
+	register("hexists", hexistsCommand, cmdRead)
+	register("hget", hgetCommand, cmdRead)
+	register("hgetall", hgetallCommand, cmdRead)
+	register("hincrby", hincrbyCommand, cmdWrite)
+	register("hkeys", hkeysCommand, cmdRead)
+	register("hlen", hlenCommand, cmdRead)
+	register("hmget", hmgetCommand, cmdRead)
+	register("hmset", hmsetCommand, cmdWrite)
+	register("hset", hsetCommand, cmdWrite)
+	register("hvals", hvalsCommand, cmdRead)
 
 	//ledisdb special command
 
-	register("hclear", hclearCommand)
-	register("hmclear", hmclearCommand)
-	register("hexpire", hexpireCommand)
-	register("hexpireat", hexpireAtCommand)
-	register("httl", httlCommand)
-	register("hpersist", hpersistCommand)
+	register("hclear", hclearCommand, cmdWrite)
+	register("hmclear", hmclearCommand, cmdWrite)
+	register("hexpire", hexpireCommand, cmdWrite)
+	register("hexpireat", hexpireAtCommand, cmdWrite)
+	register("httl", httlCommand, cmdRead)
+	register("hpersist", hpersistCommand, cmdWrite)
 }
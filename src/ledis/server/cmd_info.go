@@ -0,0 +1,192 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"ledis"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+//data types shown in info storage, in order
+var infoDataTypes = []string{"kv", "list", "hash", "zset", "bit"}
+
+type infoSection struct {
+	name string
+	dump func(app *App, buf *bytes.Buffer) error
+}
+
+var infoSections = []infoSection{
+	{"server", infoServer},
+	{"clients", infoClients},
+	{"storage", infoStorage},
+	{"binlog", infoBinLog},
+	{"replication", infoReplication},
+}
+
+func infoServer(app *App, buf *bytes.Buffer) error {
+	fmt.Fprintf(buf, "addr:%s\r\n", app.cfg.Addr)
+	fmt.Fprintf(buf, "go_version:%s\r\n", runtime.Version())
+	fmt.Fprintf(buf, "process_id:%d\r\n", os.Getpid())
+	fmt.Fprintf(buf, "uptime_in_seconds:%d\r\n", int64(time.Now().Sub(app.startTime).Seconds()))
+	return nil
+}
+
+func infoClients(app *App, buf *bytes.Buffer) error {
+	app.cfgLock.RLock()
+	maxClients := app.cfg.MaxClients
+	app.cfgLock.RUnlock()
+
+	fmt.Fprintf(buf, "connected_clients:%d\r\n", app.clientNum())
+	fmt.Fprintf(buf, "maxclients:%d\r\n", maxClients)
+	return nil
+}
+
+//format: db0:kv=10,list=0,hash=0,zset=0,bit=0,total=10, sizes are in bytes
+func infoStorage(app *App, buf *bytes.Buffer) error {
+	total, err := app.ldb.ApproximateSize()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(buf, "store_name:%s\r\n", app.ldb.StoreName())
+	fmt.Fprintf(buf, "approximate_size:%d\r\n", total)
+
+	for i := 0; i < int(ledis.MaxDBNumber); i++ {
+		db, err := app.ldb.Select(i)
+		if err != nil {
+			return err
+		}
+
+		size, err := db.ApproximateSize()
+		if err != nil {
+			return err
+		} else if size == 0 {
+			continue
+		}
+
+		sizes, err := db.ApproximateTypeSizes()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(buf, "db%d:", i)
+		for _, name := range infoDataTypes {
+			fmt.Fprintf(buf, "%s=%d,", name, sizes[ledis.DataTypeByName[name]])
+		}
+		fmt.Fprintf(buf, "total=%d\r\n", size)
+	}
+	return nil
+}
+
+//fsync latencies are in microseconds
+func infoBinLog(app *App, buf *bytes.Buffer) error {
+	st, err := app.ldb.BinLogStat()
+	if err == ledis.ErrBinLogNotEnabled {
+		fmt.Fprintf(buf, "binlog_enabled:0\r\n")
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	fs := st.FsyncStats
+
+	var avg time.Duration
+	if fs.Count > 0 {
+		avg = fs.Total / time.Duration(fs.Count)
+	}
+
+	fmt.Fprintf(buf, "binlog_enabled:1\r\n")
+	fmt.Fprintf(buf, "binlog_fsync:%s\r\n", st.Fsync)
+	fmt.Fprintf(buf, "binlog_file_index:%d\r\n", st.LogFileIndex)
+	fmt.Fprintf(buf, "binlog_file_pos:%d\r\n", st.LogPos)
+	fmt.Fprintf(buf, "binlog_last_event_id:%d\r\n", st.LastEventID)
+	fmt.Fprintf(buf, "fsync_count:%d\r\n", fs.Count)
+	fmt.Fprintf(buf, "fsync_avg_usec:%d\r\n", int64(avg/time.Microsecond))
+	fmt.Fprintf(buf, "fsync_max_usec:%d\r\n", int64(fs.Max/time.Microsecond))
+	fmt.Fprintf(buf, "fsync_last_usec:%d\r\n", int64(fs.Last/time.Microsecond))
+	return nil
+}
+
+//format: slave0:addr=127.0.0.1:1234,log_file_index=1,log_pos=100,lag_bytes=0,last_ack_seconds=0,
+//last_ack_seconds is the seconds since the slave acked last time.
+func infoReplication(app *App, buf *bytes.Buffer) error {
+	st := app.m.currentStatus()
+	if st.state == replStateNone {
+		fmt.Fprintf(buf, "role:master\r\n")
+	} else {
+		linkStatus := "down"
+		if st.state == replStateConnected {
+			linkStatus = "up"
+		}
+
+		lastSync := int64(-1)
+		if !st.lastSyncTime.IsZero() {
+			lastSync = int64(time.Now().Sub(st.lastSyncTime).Seconds())
+		}
+
+		fmt.Fprintf(buf, "role:slave\r\n")
+		fmt.Fprintf(buf, "master_addr:%s\r\n", st.masterAddr)
+		fmt.Fprintf(buf, "master_link_status:%s\r\n", linkStatus)
+		fmt.Fprintf(buf, "master_repl_state:%s\r\n", st.state)
+		fmt.Fprintf(buf, "master_last_sync_seconds_ago:%d\r\n", lastSync)
+		fmt.Fprintf(buf, "master_log_file_index:%d\r\n", st.logFileIndex)
+		fmt.Fprintf(buf, "master_log_pos:%d\r\n", st.logPos)
+	}
+
+	running, waiting := app.fullSyncNum()
+	fmt.Fprintf(buf, "fullsync_running:%d\r\n", running)
+	fmt.Fprintf(buf, "fullsync_waiting:%d\r\n", waiting)
+
+	slaves := app.slaveList()
+	now := time.Now()
+
+	fmt.Fprintf(buf, "connected_slaves:%d\r\n", len(slaves))
+	for i, s := range slaves {
+		lag, err := app.ldb.BinLogLag(s.logFileIndex, s.logPos)
+		if err != nil && err != ledis.ErrBinLogNotEnabled {
+			return err
+		}
+
+		fmt.Fprintf(buf, "slave%d:addr=%s,log_file_index=%d,log_pos=%d,lag_bytes=%d,last_ack_seconds=%d\r\n",
+			i, s.addr, s.logFileIndex, s.logPos, lag, int64(now.Sub(s.ackTime).Seconds()))
+	}
+	return nil
+}
+
+//info [section]
+func infoCommand(c *client) error {
+	if len(c.args) > 1 {
+		return ErrCmdParams
+	}
+
+	section := "all"
+	if len(c.args) == 1 {
+		section = strings.ToLower(ledis.String(c.args[0]))
+	}
+
+	var buf bytes.Buffer
+	for _, s := range infoSections {
+		if section != "all" && section != "default" && section != s.name {
+			continue
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteString("\r\n")
+		}
+
+		fmt.Fprintf(&buf, "# %s\r\n", strings.Title(s.name))
+		if err := s.dump(c.app, &buf); err != nil {
+			return err
+		}
+	}
+
+	c.writeBulk(buf.Bytes())
+	return nil
+}
+
+func init() {
+	register("info", infoCommand, cmdRead)
+}
@@ -278,20 +278,20 @@ func persistCommand(c *client) error {
 // func (db *DB) TTL(key []byte) (int64, error)
 
 func init() {
-	register("decr", decrCommand)
-	register("decrby", decrbyCommand)
-	register("del", delCommand)
-	register("exists", existsCommand)
-	register("get", getCommand)
-	register("getset", getsetCommand)
-	register("incr", incrCommand)
-	register("incrby", incrbyCommand)
-	register("mget", mgetCommand)
-	register("mset", msetCommand)
-	register("set", setCommand)
-	register("setnx", setnxCommand)
-	register("expire", expireCommand)
-	register("expireat", expireAtCommand)
-	register("ttl", ttlCommand)
-	register("persist", persistCommand)
+	register("decr", decrCommand, cmdWrite)
+	register("decrby", decrbyCommand, cmdWrite)
+	register("del", delCommand, cmdWrite)
+	register("exists", existsCommand, cmdRead)
+	register("get", getCommand, cmdRead)
+	register("getset", getsetCommand, cmdWrite)
+	register("incr", incrCommand, cmdWrite)
+	register("incrby", incrbyCommand, cmdWrite)
+	register("mget", mgetCommand, cmdRead)
+	register("mset", msetCommand, cmdWrite)
+	register("set", setCommand, cmdWrite)
+	register("setnx", setnxCommand, cmdWrite)
+	register("expire", expireCommand, cmdWrite)
+	register("expireat", expireAtCommand, cmdWrite)
+	register("ttl", ttlCommand, cmdRead)
+	register("persist", persistCommand, cmdWrite)
 }
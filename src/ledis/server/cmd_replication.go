@@ -3,12 +3,12 @@ package server
 import (
 	"encoding/binary"
 	"fmt"
+	"github.com/siddontang/go-log/log"
 	"github.com/siddontang/go-snappy/snappy"
 	"ledis"
-	"io/ioutil"
-	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func slaveofCommand(c *client) error {
@@ -40,32 +40,57 @@ func slaveofCommand(c *client) error {
 	return nil
 }
 
+//fullsync [key]
+//
+//replies status FULLSYNC, then the dump of the data after the key in bulk chunks as it is made,
+//ends with a null bulk, an error reply instead of a chunk means the dump is failed.
+//A slave resumes a broken fullsync with the last key it has loaded.
+//
+//At most max_parallel_fullsyncs run at the same time, the others wait before replying.
 func fullsyncCommand(c *client) error {
-	//todo, multi fullsync may use same dump file
-	dumpFile, err := ioutil.TempFile(c.app.cfg.DataDir, "dump_")
-	if err != nil {
-		return err
+	if len(c.args) > 1 {
+		return ErrCmdParams
 	}
 
-	if err = c.app.ldb.Dump(dumpFile); err != nil {
-		return err
+	if !c.app.acquireFullSync() {
+		return ErrShutdown
 	}
+	defer c.app.releaseFullSync()
 
-	st, _ := dumpFile.Stat()
-	n := st.Size()
-
-	dumpFile.Seek(0, os.SEEK_SET)
+	var key []byte
+	if len(c.args) == 1 {
+		key = c.args[0]
+	}
 
-	c.writeBulkFrom(n, dumpFile)
+	c.writeStatus("FULLSYNC")
 
-	name := dumpFile.Name()
-	dumpFile.Close()
+	//the binlog from the dump position is kept until the slave syncs from it,
+	//syncCommand moves the index later on the same connection.
+	started := func(info *ledis.MasterInfo) {
+		c.infoLock.Lock()
+		c.syncLogIndex = info.LogFileIndex
+		c.infoLock.Unlock()
+	}
 
-	os.Remove(name)
+	if err := c.app.ldb.DumpAfter(chunkWriter{c}, key, started); err != nil {
+		return err
+	}
 
+	//the end of dump
+	c.writeBulk(nil)
 	return nil
 }
 
+//chunkWriter sends every write as a bulk chunk
+type chunkWriter struct {
+	c *client
+}
+
+func (w chunkWriter) Write(p []byte) (int, error) {
+	w.c.writeBulk(p)
+	return len(p), w.c.wb.Flush()
+}
+
 var reserveInfoSpace = make([]byte, 16)
 
 func syncCommand(c *client) error {
@@ -87,39 +112,272 @@ func syncCommand(c *client) error {
 		return ErrCmdParams
 	}
 
+	//the slave has read the binlog files before logIndex
+	c.setSlavePos(logIndex, logPos)
+
+	m := &ledis.MasterInfo{LogFileIndex: logIndex, LogPos: logPos}
+
+	_, err = c.writeSyncData(m)
+	return err
+}
+
+//setSlavePos records the binlog position the slave client has applied
+func (c *client) setSlavePos(logIndex int64, logPos int64) {
+	c.infoLock.Lock()
+	c.syncLogIndex = logIndex
+	c.ackLogIndex = logIndex
+	c.ackLogPos = logPos
+	c.ackTime = time.Now()
+	c.infoLock.Unlock()
+}
+
+//writeSyncData writes the events from the position in m as a bulk of snappy compressed data,
+//the data is the next position (16 bytes) then the events, m is updated to the next position.
+func (c *client) writeSyncData(m *ledis.MasterInfo) (int, error) {
 	c.syncBuf.Reset()
 
 	//reserve space to write master info
 	if _, err := c.syncBuf.Write(reserveInfoSpace); err != nil {
-		return err
+		return 0, err
+	}
+
+	n, err := c.app.ldb.ReadEventsTo(m, &c.syncBuf)
+	if err != nil {
+		return 0, err
 	}
 
-	m := &ledis.MasterInfo{logIndex, logPos}
+	buf := c.syncBuf.Bytes()
 
-	if _, err := c.app.ldb.ReadEventsTo(m, &c.syncBuf); err != nil {
-		return err
-	} else {
-		buf := c.syncBuf.Bytes()
+	binary.BigEndian.PutUint64(buf[0:], uint64(m.LogFileIndex))
+	binary.BigEndian.PutUint64(buf[8:], uint64(m.LogPos))
 
-		binary.BigEndian.PutUint64(buf[0:], uint64(m.LogFileIndex))
-		binary.BigEndian.PutUint64(buf[8:], uint64(m.LogPos))
+	if len(c.compressBuf) < snappy.MaxEncodedLen(len(buf)) {
+		c.compressBuf = make([]byte, snappy.MaxEncodedLen(len(buf)))
+	}
 
-		if len(c.compressBuf) < snappy.MaxEncodedLen(len(buf)) {
-			c.compressBuf = make([]byte, snappy.MaxEncodedLen(len(buf)))
-		}
+	if buf, err = snappy.Encode(c.compressBuf, buf); err != nil {
+		return 0, err
+	}
 
-		if buf, err = snappy.Encode(c.compressBuf, buf); err != nil {
+	c.writeBulk(buf)
+	return n, nil
+}
+
+const (
+	//the master sends the position every interval when no new event
+	replHeartbeat = 1 * time.Second
+
+	//the connection is broken if nothing is read for the timeout
+	replTimeout = 10 * time.Second
+)
+
+//psync index pos
+//
+//pushes the events from the position as soon as they are committed, in the same format as sync,
+//until the connection is closed, the slave acks the position applied by "ack index pos".
+func psyncCommand(c *client) error {
+	args := c.args
+	if len(args) != 2 {
+		return ErrCmdParams
+	}
+
+	logIndex, err := ledis.StrInt64(args[0], nil)
+	if err != nil {
+		return ErrCmdParams
+	}
+
+	logPos, err := ledis.StrInt64(args[1], nil)
+	if err != nil {
+		return ErrCmdParams
+	}
+
+	c.setSlavePos(logIndex, logPos)
+
+	done := make(chan struct{})
+	go c.readAcks(done)
+
+	//the connection is used by psync only, closed when psync finishes
+	defer func() {
+		c.c.Close()
+		<-done
+
+		//not a slave now
+		c.infoLock.Lock()
+		c.syncLogIndex = 0
+		c.ackLogIndex = 0
+		c.ackLogPos = 0
+		c.infoLock.Unlock()
+	}()
+
+	m := &ledis.MasterInfo{LogFileIndex: logIndex, LogPos: logPos}
+	for {
+		notify := c.app.ldb.BinLogNotify()
+
+		lastIndex, lastPos := m.LogFileIndex, m.LogPos
+		if _, err = c.writeSyncData(m); err != nil {
+			return err
+		} else if err = c.wb.Flush(); err != nil {
 			return err
 		}
 
-		c.writeBulk(buf)
+		if m.LogFileIndex <= 0 {
+			//the slave has to fullsync or stop
+			return nil
+		} else if m.LogFileIndex != lastIndex || m.LogPos != lastPos {
+			continue
+		}
+
+		select {
+		case <-notify:
+		case <-time.After(replHeartbeat):
+		case <-done:
+			return nil
+		case <-c.app.quit:
+			return nil
+		}
+	}
+}
+
+//readAcks reads "ack index pos" from the slave until error, then closes done.
+func (c *client) readAcks(done chan struct{}) {
+	defer close(done)
+
+	for {
+		c.c.SetReadDeadline(time.Now().Add(replTimeout))
+
+		req, err := c.readRequest()
+		if err != nil {
+			return
+		} else if len(req) != 3 || strings.ToLower(ledis.String(req[0])) != "ack" {
+			log.Error("invalid ack from slave %s", c.addr)
+			return
+		}
+
+		index, err := ledis.StrInt64(req[1], nil)
+		if err != nil {
+			return
+		}
+
+		pos, err := ledis.StrInt64(req[2], nil)
+		if err != nil {
+			return
+		}
+
+		c.setSlavePos(index, pos)
+
+		c.app.notifyAck()
+	}
+}
+
+//role
+//
+//master: "master", binlog index, binlog pos, and addr, index and pos applied of every slave
+//slave: "slave", master addr, replication state, index and pos applied from master
+func roleCommand(c *client) error {
+	if len(c.args) != 0 {
+		return ErrCmdParams
+	}
+
+	st := c.app.m.currentStatus()
+	if st.state != replStateNone {
+		c.writeArray([]interface{}{
+			[]byte("slave"),
+			[]byte(st.masterAddr),
+			[]byte(st.state),
+			st.logFileIndex,
+			st.logPos,
+		})
+		return nil
+	}
+
+	var logIndex, logPos int64
+	if bst, err := c.ldb.BinLogStat(); err == nil {
+		logIndex, logPos = bst.LogFileIndex, bst.LogPos
+	} else if err != ledis.ErrBinLogNotEnabled {
+		return err
+	}
+
+	slaves := []interface{}{}
+	for _, s := range c.app.slaveList() {
+		slaves = append(slaves, []interface{}{[]byte(s.addr), s.logFileIndex, s.logPos})
+	}
+
+	c.writeArray([]interface{}{[]byte("master"), logIndex, logPos, slaves})
+	return nil
+}
+
+//wait numslaves timeout
+//
+//waits numslaves slaves to ack current binlog position for timeout milliseconds, 0 means no timeout,
+//returns the number of slaves acked.
+func waitCommand(c *client) error {
+	args := c.args
+	if len(args) != 2 {
+		return ErrCmdParams
+	}
+
+	num, err := ledis.StrInt64(args[0], nil)
+	if err != nil || num < 0 {
+		return ErrCmdParams
+	}
+
+	timeout, err := ledis.StrInt64(args[1], nil)
+	if err != nil || timeout < 0 {
+		return ErrCmdParams
+	}
+
+	st, err := c.ldb.BinLogStat()
+	if err == ledis.ErrBinLogNotEnabled {
+		//no slave can sync
+		c.writeInteger(0)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	n := c.app.waitSlaves(int(num), st.LogFileIndex, st.LogPos, time.Duration(timeout)*time.Millisecond)
+
+	c.writeInteger(int64(n))
+	return nil
+}
+
+//purge binlogs to index
+//purge binlogs before unix_time
+func purgeCommand(c *client) error {
+	args := c.args
+	if len(args) != 3 || strings.ToLower(ledis.String(args[0])) != "binlogs" {
+		return ErrCmdParams
+	}
+
+	v, err := ledis.StrInt64(args[2], nil)
+	if err != nil {
+		return ErrCmdParams
+	}
+
+	var n int
+	switch strings.ToLower(ledis.String(args[1])) {
+	case "to":
+		n, err = c.ldb.PurgeBinLogsTo(v)
+	case "before":
+		n, err = c.ldb.PurgeBinLogsBefore(time.Unix(v, 0))
+	default:
+		return ErrCmdParams
+	}
+
+	if err != nil {
+		return err
 	}
 
+	c.writeInteger(int64(n))
 	return nil
 }
 
 func init() {
-	register("slaveof", slaveofCommand)
-	register("fullsync", fullsyncCommand)
-	register("sync", syncCommand)
+	register("purge", purgeCommand, cmdRead)
+	register("slaveof", slaveofCommand, cmdRead)
+	register("fullsync", fullsyncCommand, cmdRead)
+	register("sync", syncCommand, cmdRead)
+	register("psync", psyncCommand, cmdRead)
+	register("wait", waitCommand, cmdRead)
+	register("role", roleCommand, cmdRead)
 }
@@ -1,16 +1,23 @@
 package server
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"ledis"
+	ledis_client "ledis/client"
 	"os"
+	"store"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
-	"leveldb"
 )
 
 func checkDataEqual(master *App, slave *App) error {
-	it := master.ldb.DataDB().RangeLimitIterator(nil, nil, leveldb.RangeClose, 0, -1)
+	it := master.ldb.DataDB().RangeLimitIterator([]byte{0}, []byte{ledis.MaxDBNumber}, store.RangeROpen, 0, -1)
 	for ; it.Valid(); it.Next() {
 		key := it.Key()
 		value := it.Value()
@@ -104,4 +111,360 @@ func TestReplication(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	//the binlog the slave is syncing is kept
+	if index := master.slavesLogIndex(); index != 1 {
+		t.Fatal(index)
+	}
+
+	//the events are pushed to slave at once
+	db.Set([]byte("a4"), value)
+
+	slaveDB, _ := slave.ldb.Select(0)
+	for i := 0; ; i++ {
+		if v, _ := slaveDB.Get([]byte("a4")); v != nil {
+			break
+		} else if i >= 50 {
+			t.Fatal("replication lag too large")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	//the slave acks the position
+	time.Sleep(100 * time.Millisecond)
+
+	var acked bool
+	for _, c := range master.clientList() {
+		c.infoLock.Lock()
+		acked = acked || (c.ackLogIndex == 1 && c.ackLogPos > 0)
+		c.infoLock.Unlock()
+	}
+
+	if !acked {
+		t.Fatal("slave must ack")
+	}
+
+	mc := ledis_client.NewClient(&ledis_client.Config{Addr: masterCfg.Addr, MaxIdleConns: 1}).Get()
+	defer mc.Close()
+
+	if err = mc.Set([]byte("a5"), value); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := mc.Wait(1, 1000); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatal(n)
+	}
+
+	if ay, err := mc.Role(); err != nil {
+		t.Fatal(err)
+	} else if len(ay) != 4 || string(ay[0].([]byte)) != "master" {
+		t.Fatal(ay)
+	} else if slaves := ay[3].([]interface{}); len(slaves) != 1 {
+		t.Fatal(slaves)
+	} else if s := slaves[0].([]interface{}); s[1].(int64) != ay[1].(int64) || s[2].(int64) != ay[2].(int64) {
+		t.Fatal(s, ay)
+	}
+
+	if s, err := mc.Info("replication"); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(s, "role:master\r\n") || !strings.Contains(s, "connected_slaves:1\r\n") || !strings.Contains(s, "lag_bytes=0,last_ack_seconds=") {
+		t.Fatal(s)
+	}
+
+	sc := ledis_client.NewClient(&ledis_client.Config{Addr: slaveCfg.Addr, MaxIdleConns: 1}).Get()
+	defer sc.Close()
+
+	if ay, err := sc.Role(); err != nil {
+		t.Fatal(err)
+	} else if len(ay) != 5 || string(ay[0].([]byte)) != "slave" || string(ay[1].([]byte)) != masterCfg.Addr ||
+		string(ay[2].([]byte)) != "connected" {
+		t.Fatal(ay)
+	}
+
+	if s, err := sc.Info("replication"); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(s, "master_link_status:up\r\n") || !strings.Contains(s, "master_last_sync_seconds_ago:0\r\n") {
+		t.Fatal(s)
+	}
+
+	//writes are rejected on read only slave by default
+
+	if err = sc.Set([]byte("s1"), value); err == nil || !strings.HasPrefix(err.Error(), "READONLY") {
+		t.Fatal(err)
+	} else if v, err := sc.Get([]byte("a5")); err != nil {
+		t.Fatal(err)
+	} else if v == nil {
+		t.Fatal("must replicated")
+	}
+
+	if _, err = sc.Do("config", "set", "slave_read_only", "false"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = sc.Set([]byte("s1"), value); err != nil {
+		t.Fatal(err)
+	}
+
+	//writes wait slaves to ack
+	master.cfgLock.Lock()
+	master.cfg.MinSlavesToAck = 1
+	master.cfgLock.Unlock()
+
+	if err = mc.Set([]byte("a6"), value); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, _ := slaveDB.Get([]byte("a6")); v == nil {
+		t.Fatal("must be replicated before reply")
+	}
+
+	master.cfgLock.Lock()
+	master.cfg.MinSlavesToAck = 2
+	master.cfg.MinSlavesAckTimeout = 100
+	master.cfgLock.Unlock()
+
+	if err = mc.Set([]byte("a7"), value); err == nil || !strings.Contains(err.Error(), ErrNoSlavesAck.Error()) {
+		t.Fatal(err)
+	}
+
+	//a write command changing nothing does not wait
+	if n, err := ledis_client.Int64(mc.Do("expire", "not_exist", 100)); err != nil {
+		t.Fatal(err)
+	} else if n != 0 {
+		t.Fatal(n)
+	}
+
+	//the reads do not wait
+	if v, err := mc.Get([]byte("a7")); err != nil {
+		t.Fatal(err)
+	} else if v == nil {
+		t.Fatal("write is done even not acked")
+	}
+}
+
+func TestFullSyncResume(t *testing.T) {
+	data_dir := "/tmp/test_fullsync_resume"
+	os.RemoveAll(data_dir)
+
+	masterCfg := new(Config)
+	masterCfg.DataDir = fmt.Sprintf("%s/master", data_dir)
+	masterCfg.Addr = "127.0.0.1:11184"
+	masterCfg.BinLog.Use = true
+
+	master, err := NewApp(masterCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer master.Close()
+
+	go master.Run()
+
+	db, _ := master.ldb.Select(0)
+	db.Set([]byte("a"), []byte("1"))
+	db.Set([]byte("b"), []byte("2"))
+	db.Set([]byte("c"), []byte("3"))
+
+	slaveCfg := new(Config)
+	slaveCfg.DataDir = fmt.Sprintf("%s/slave", data_dir)
+	slaveCfg.Addr = "127.0.0.1:11185"
+
+	slave, err := NewApp(slaveCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slave.Close()
+
+	it := master.ldb.DataDB().RangeLimitIterator([]byte{0}, []byte{ledis.MaxDBNumber}, store.RangeROpen, 0, 1)
+	firstKey := it.Key()
+	it.Close()
+
+	//the first key was loaded before the fullsync is broken
+	slave.ldb.DataDB().Put(firstKey, []byte("old"))
+
+	m := slave.m
+	m.info.Addr = masterCfg.Addr
+	m.info.FullSyncKey = firstKey
+	m.info.FullSyncLogFileIndex = 1
+	m.info.FullSyncLogPos = 10
+
+	if err = m.connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if err = m.fullSync(); err != nil {
+		t.Fatal(err)
+	}
+
+	//resumed from the first dump position, the data before checkpoint is kept
+	if m.info.LogFileIndex != 1 || m.info.LogPos != 10 || m.info.FullSyncKey != nil {
+		t.Fatal(m.info)
+	}
+
+	sdb, _ := slave.ldb.Select(0)
+	if v, _ := sdb.Get([]byte("a")); string(v) != "old" {
+		t.Fatal(string(v))
+	} else if v, _ := sdb.Get([]byte("c")); string(v) != "3" {
+		t.Fatal(string(v))
+	}
+
+	//a new fullsync loads all
+	if err = m.fullSync(); err != nil {
+		t.Fatal(err)
+	} else if err = checkDataEqual(master, slave); err != nil {
+		t.Fatal(err)
+	}
+
+	//the binlog from the dump position is in use until the slave syncs
+	if index := master.slavesLogIndex(); index == 0 || index != m.info.LogFileIndex {
+		t.Fatal(index, m.info.LogFileIndex)
+	}
+}
+
+func TestFullSyncReader(t *testing.T) {
+	read := func(reply string) (string, error) {
+		r, err := NewFullSyncReader(bufio.NewReader(strings.NewReader(reply)))
+		if err != nil {
+			return "", err
+		}
+
+		var buf bytes.Buffer
+		_, err = io.Copy(&buf, r)
+		return buf.String(), err
+	}
+
+	if s, err := read("+FULLSYNC\r\n$3\r\nabc\r\n$0\r\n\r\n$2\r\nde\r\n$-1\r\n"); err != nil {
+		t.Fatal(err)
+	} else if s != "abcde" {
+		t.Fatal(s)
+	}
+
+	//the dump in a single bulk by old versions
+	if s, err := read("$3\r\nabc\r\n$2\r\nde\r\n"); err != nil {
+		t.Fatal(err)
+	} else if s != "abc" {
+		t.Fatal(s)
+	}
+
+	if _, err := read("+FULLSYNC\r\n$3\r\nabc\r\n-ERR dump failed\r\n"); err == nil || err.Error() != "ERR dump failed" {
+		t.Fatal(err)
+	}
+
+	if _, err := read("+FULLSYNC\r\n$3\r\nabc\r\n"); err != io.ErrUnexpectedEOF && err != io.EOF {
+		t.Fatal(err)
+	}
+}
+
+func TestMaxParallelFullSyncs(t *testing.T) {
+	startTestApp()
+
+	app := testApp
+
+	app.cfgLock.Lock()
+	app.cfg.MaxParallelFullSyncs = 1
+	app.cfgLock.Unlock()
+
+	defer func() {
+		app.cfgLock.Lock()
+		app.cfg.MaxParallelFullSyncs = 0
+		app.cfgLock.Unlock()
+	}()
+
+	if !app.acquireFullSync() {
+		t.Fatal("must acquire")
+	}
+
+	acquired := make(chan bool)
+	go func() {
+		acquired <- app.acquireFullSync()
+	}()
+
+	//the second waits the first to finish
+	for i := 0; i < 100; i++ {
+		if _, waiting := app.fullSyncNum(); waiting == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if running, waiting := app.fullSyncNum(); running != 1 || waiting != 1 {
+		t.Fatal(running, waiting)
+	}
+
+	app.releaseFullSync()
+
+	if !<-acquired {
+		t.Fatal("must acquire")
+	} else if running, waiting := app.fullSyncNum(); running != 1 || waiting != 0 {
+		t.Fatal(running, waiting)
+	}
+
+	app.releaseFullSync()
+}
+
+func TestReplicationApplyFailure(t *testing.T) {
+	data_dir := "/tmp/test_replication_apply"
+	os.RemoveAll(data_dir)
+
+	masterCfg := new(Config)
+	masterCfg.DataDir = fmt.Sprintf("%s/master", data_dir)
+	masterCfg.Addr = "127.0.0.1:11188"
+	masterCfg.BinLog.Use = true
+
+	master, err := NewApp(masterCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer master.Close()
+
+	slaveCfg := new(Config)
+	slaveCfg.DataDir = fmt.Sprintf("%s/slave", data_dir)
+	slaveCfg.Addr = "127.0.0.1:11189"
+	slaveCfg.SlaveOf = masterCfg.Addr
+
+	slave, err := NewApp(slaveCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slave.Close()
+
+	//fails once when applying the events of key apply_fail
+	var failed int32
+	errApply := errors.New("apply failed")
+	syncDataFailpoint = func(data []byte) error {
+		if bytes.Contains(data, []byte("apply_fail")) && atomic.CompareAndSwapInt32(&failed, 0, 1) {
+			return errApply
+		}
+		return nil
+	}
+	defer func() {
+		slave.Close()
+		syncDataFailpoint = nil
+	}()
+
+	go master.Run()
+	go slave.Run()
+
+	db, _ := master.ldb.Select(0)
+	db.Set([]byte("a"), []byte("1"))
+
+	time.Sleep(500 * time.Millisecond)
+
+	db.Set([]byte("apply_fail"), []byte("2"))
+
+	//synced again after reconnecting
+	slaveDB, _ := slave.ldb.Select(0)
+	for i := 0; i < 50; i++ {
+		if v, _ := slaveDB.Get([]byte("apply_fail")); v != nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if atomic.LoadInt32(&failed) != 1 {
+		t.Fatal("apply must fail once")
+	} else if err = checkDataEqual(master, slave); err != nil {
+		t.Fatal(err)
+	}
 }
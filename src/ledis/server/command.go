@@ -10,14 +10,30 @@ import (
 
 type CommandFunc func(c *client) error
 
-var regCmds = map[string]CommandFunc{}
+//every command is tagged as read or write
+const (
+	//the command does not change data
+	cmdRead = 1 << iota
 
-func register(name string, f CommandFunc) {
+	//the command changes data, rejected on read only slaves
+	cmdWrite
+)
+
+type command struct {
+	f    CommandFunc
+	flag int
+}
+
+var regCmds = map[string]*command{}
+
+func register(name string, f CommandFunc, flag int) {
 	if _, ok := regCmds[strings.ToLower(name)]; ok {
 		panic(fmt.Sprintf("%s has been registered", name))
+	} else if flag != cmdRead && flag != cmdWrite {
+		panic(fmt.Sprintf("%s must be tagged as read or write", name))
 	}
 
-	regCmds[name] = f
+	regCmds[name] = &command{f, flag}
 }
 
 func pingCommand(c *client) error {
@@ -45,15 +61,97 @@ func selectCommand(c *client) error {
 		if db, err := c.ldb.Select(index); err != nil {
 			return err
 		} else {
+			c.infoLock.Lock()
 			c.db = db
+			c.infoLock.Unlock()
+
 			c.writeStatus(OK)
 		}
 	}
 	return nil
 }
 
+func shutdownCommand(c *client) error {
+	if len(c.args) != 0 {
+		return ErrCmdParams
+	}
+
+	c.writeStatus(OK)
+
+	//Close waits all clients to finish, so we can not call it in this client
+	go c.app.Close()
+	return nil
+}
+
+//compact [index [type]]
+func compactCommand(c *client) error {
+	args := c.args
+	if len(args) > 2 {
+		return ErrCmdParams
+	}
+
+	if len(args) == 0 {
+		if err := c.ldb.Compact(); err != nil {
+			return err
+		}
+
+		c.writeStatus(OK)
+		return nil
+	}
+
+	index, err := strconv.Atoi(ledis.String(args[0]))
+	if err != nil {
+		return ErrCmdParams
+	}
+
+	db, err := c.ldb.Select(index)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		err = db.Compact()
+	} else {
+		dataType, ok := ledis.DataTypeByName[strings.ToLower(ledis.String(args[1]))]
+		if !ok {
+			return ErrCmdParams
+		}
+
+		err = db.CompactType(dataType)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c.writeStatus(OK)
+	return nil
+}
+
+//backup path
+func backupCommand(c *client) error {
+	if len(c.args) != 1 {
+		return ErrCmdParams
+	}
+
+	dir, err := c.app.cfg.backupPath(ledis.String(c.args[0]))
+	if err != nil {
+		return err
+	}
+
+	if _, err = c.ldb.Backup(dir); err != nil {
+		return err
+	}
+
+	c.writeStatus(OK)
+	return nil
+}
+
 func init() {
-	register("ping", pingCommand)
-	register("echo", echoCommand)
-	register("select", selectCommand)
+	register("ping", pingCommand, cmdRead)
+	register("echo", echoCommand, cmdRead)
+	register("select", selectCommand, cmdRead)
+	register("shutdown", shutdownCommand, cmdRead)
+	register("compact", compactCommand, cmdRead)
+	register("backup", backupCommand, cmdRead)
 }
@@ -0,0 +1,319 @@
+package server
+
+import (
+	ledis_client "ledis/client"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+//commands used between master and slave or only in tests have no typed api
+var innerCmds = map[string]bool{
+	"fullsync":   true,
+	"sync":       true,
+	"psync":      true,
+	"test_block": true,
+}
+
+//commands with sub commands have a typed method for each,
+//e.g, ClientList for "client list"
+var subCmds = map[string][]string{
+	"client": {"list", "kill", "setname", "getname"},
+	"config": {"get", "set", "rewrite"},
+	"purge":  {"binlogsto", "binlogsbefore"},
+}
+
+func TestTypedAPISync(t *testing.T) {
+	methods := make(map[string]bool)
+	tp := reflect.TypeOf(new(ledis_client.Conn))
+	for i := 0; i < tp.NumMethod(); i++ {
+		methods[strings.ToLower(tp.Method(i).Name)] = true
+	}
+
+	for name := range regCmds {
+		if innerCmds[name] {
+			continue
+		}
+
+		names := []string{name}
+		if subs, ok := subCmds[name]; ok {
+			names = names[:0]
+			for _, sub := range subs {
+				names = append(names, name+sub)
+			}
+		}
+
+		for _, n := range names {
+			if !methods[n] {
+				t.Errorf("client has no typed method for command %s", n)
+			}
+		}
+	}
+}
+
+func TestTypedAPI(t *testing.T) {
+	c := getTestConn()
+	defer c.Close()
+
+	if err := c.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Set([]byte("typed_a"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := c.Incr([]byte("typed_a")); err != nil {
+		t.Fatal(err)
+	} else if n != 2 {
+		t.Fatal(n)
+	}
+
+	if v, err := c.Get([]byte("typed_empty")); err != nil {
+		t.Fatal(err)
+	} else if v != nil {
+		t.Fatal(v)
+	}
+
+	if v, err := c.MGet([]byte("typed_a"), []byte("typed_empty")); err != nil {
+		t.Fatal(err)
+	} else if len(v) != 2 || string(v[0]) != "2" || v[1] != nil {
+		t.Fatal(v)
+	}
+
+	if err := c.HMSet([]byte("typed_h"), ledis_client.FVPair{Field: []byte("f1"), Value: []byte("v1")},
+		ledis_client.FVPair{Field: []byte("f2"), Value: []byte("v2")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if m, err := c.HGetAll([]byte("typed_h")); err != nil {
+		t.Fatal(err)
+	} else if len(m) != 2 || string(m["f1"]) != "v1" || string(m["f2"]) != "v2" {
+		t.Fatal(m)
+	}
+
+	if n, err := c.RPush([]byte("typed_l"), []byte("1"), []byte("2")); err != nil {
+		t.Fatal(err)
+	} else if n != 2 {
+		t.Fatal(n)
+	}
+
+	if v, err := c.LRange([]byte("typed_l"), 0, -1); err != nil {
+		t.Fatal(err)
+	} else if len(v) != 2 || string(v[1]) != "2" {
+		t.Fatal(v)
+	}
+
+	if n, err := c.ZAdd([]byte("typed_z"), ledis_client.ScorePair{Score: 1, Member: []byte("a")},
+		ledis_client.ScorePair{Score: 2, Member: []byte("b")}); err != nil {
+		t.Fatal(err)
+	} else if n != 2 {
+		t.Fatal(n)
+	}
+
+	if n, err := c.ZCount([]byte("typed_z"), math.MinInt64, math.MaxInt64); err != nil {
+		t.Fatal(err)
+	} else if n != 2 {
+		t.Fatal(n)
+	}
+
+	if v, err := c.ZRevRangeByScoreWithScores([]byte("typed_z"), math.MaxInt64, math.MinInt64, 0, -1); err != nil {
+		t.Fatal(err)
+	} else if len(v) != 2 || string(v[0].Member) != "b" || v[0].Score != 2 {
+		t.Fatal(v)
+	}
+
+	if n, err := c.ZRank([]byte("typed_z"), []byte("c")); err != nil {
+		t.Fatal(err)
+	} else if n != -1 {
+		t.Fatal(n)
+	}
+
+	if _, err := c.ZScore([]byte("typed_z"), []byte("c")); err != ledis_client.ErrNil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.BSetBit([]byte("typed_b"), 7, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := c.BCount([]byte("typed_b"), 0, -1); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatal(n)
+	}
+
+	if err := c.Select(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := c.Get([]byte("typed_a")); err != nil {
+		t.Fatal(err)
+	} else if v != nil {
+		t.Fatal(v)
+	}
+
+	if err := c.Select(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.ClientSetName("typed"); err != nil {
+		t.Fatal(err)
+	}
+
+	if name, err := c.ClientGetName(); err != nil {
+		t.Fatal(err)
+	} else if name != "typed" {
+		t.Fatal(name)
+	}
+}
+
+func TestCompactCommand(t *testing.T) {
+	c := getTestConn()
+	defer c.Close()
+
+	if err := c.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.CompactDB(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.CompactType(0, "zset"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.CompactType(0, "none"); err == nil {
+		t.Fatal("must error")
+	}
+
+	if err := c.CompactDB(100); err == nil {
+		t.Fatal("must error")
+	}
+}
+
+func TestInfoCommand(t *testing.T) {
+	c := getTestConn()
+	defer c.Close()
+
+	c.Set([]byte("info_key"), []byte("1"))
+
+	if s, err := c.Info(""); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(s, "# Server\r\n") || !strings.Contains(s, "# Storage\r\n") {
+		t.Fatal(s)
+	}
+
+	if s, err := c.Info("binlog"); err != nil {
+		t.Fatal(err)
+	} else if s != "# Binlog\r\nbinlog_enabled:0\r\n" {
+		t.Fatal(s)
+	}
+
+	if s, err := c.Info("clients"); err != nil {
+		t.Fatal(err)
+	} else if !strings.HasPrefix(s, "# Clients\r\nconnected_clients:") {
+		t.Fatal(s)
+	} else if strings.Contains(s, "# Server") {
+		t.Fatal(s)
+	}
+
+	if s, err := c.Info("storage"); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(s, "store_name:") {
+		t.Fatal(s)
+	}
+}
+
+func TestBackupCommand(t *testing.T) {
+	c := getTestConn()
+	defer c.Close()
+
+	os.RemoveAll("/tmp/testdb/backup")
+
+	if err := c.Backup("20141019"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat("/tmp/testdb/backup/20141019/backup.info"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Backup("20141019"); err == nil {
+		t.Fatal("must error")
+	}
+
+	//can not write out of data_dir/backup
+	for _, dir := range []string{"/tmp/testdb_backup", "../data", "a/../../data", "."} {
+		if err := c.Backup(dir); err == nil || !strings.Contains(err.Error(), ErrBackupPath.Error()) {
+			t.Fatal(dir, err)
+		}
+	}
+}
+
+func TestPurgeCommand(t *testing.T) {
+	c := getTestConn()
+	defer c.Close()
+
+	//binlog is not enabled in test server
+	if _, err := c.PurgeBinLogsTo(1); err == nil || !strings.Contains(err.Error(), "not enabled") {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Do("purge", "binlogs", "after", 1); err == nil {
+		t.Fatal("must error")
+	}
+}
+
+func TestWaitCommand(t *testing.T) {
+	c := getTestConn()
+	defer c.Close()
+
+	//no slave without binlog
+	if n, err := c.Wait(1, 0); err != nil {
+		t.Fatal(err)
+	} else if n != 0 {
+		t.Fatal(n)
+	}
+
+	if _, err := c.Wait(-1, 0); err == nil {
+		t.Fatal("must error")
+	}
+}
+
+func TestRoleCommand(t *testing.T) {
+	c := getTestConn()
+	defer c.Close()
+
+	//master without binlog and slaves
+	if ay, err := c.Role(); err != nil {
+		t.Fatal(err)
+	} else if len(ay) != 4 || string(ay[0].([]byte)) != "master" || ay[1].(int64) != 0 {
+		t.Fatal(ay)
+	} else if len(ay[3].([]interface{})) != 0 {
+		t.Fatal(ay)
+	}
+
+	if s, err := c.Info("replication"); err != nil {
+		t.Fatal(err)
+	} else if s != "# Replication\r\nrole:master\r\nfullsync_running:0\r\nfullsync_waiting:0\r\nconnected_slaves:0\r\n" {
+		t.Fatal(s)
+	}
+}
+
+func TestCommandFlags(t *testing.T) {
+	for _, name := range []string{"set", "del", "expire", "hset", "lpush", "zadd", "bsetbit", "bopt"} {
+		if regCmds[name].flag != cmdWrite {
+			t.Fatal(name, "must be write")
+		}
+	}
+
+	for _, name := range []string{"get", "ttl", "hgetall", "lrange", "zrange", "bget", "info", "slaveof"} {
+		if regCmds[name].flag != cmdRead {
+			t.Fatal(name, "must be read")
+		}
+	}
+}
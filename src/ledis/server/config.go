@@ -2,28 +2,63 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/siddontang/copier"
-	"ledis"
 	"io/ioutil"
+	"ledis"
+	"os"
+	"path"
+	"strings"
+	"time"
 )
 
+const defaultShutdownTimeout = 10
+
+const defaultMinSlavesAckTimeout = 10000
+
+const defaultMaxParallelFullSyncs = 2
+
 type Config struct {
 	Addr string `json:"addr"`
 
 	DataDir string `json:"data_dir"`
 
 	DB struct {
+		//store driver name, goleveldb, memory or leveldb (build with -tags leveldb)
+		Name string `json:"name"`
+
 		Compression     bool `json:"compression"`
 		BlockSize       int  `json:"block_size"`
 		WriteBufferSize int  `json:"write_buffer_size"`
 		CacheSize       int  `json:"cache_size"`
 		MaxOpenFiles    int  `json:"max_open_files"`
+
+		//bloom filter bits per key, default 10
+		BloomBits int `json:"bloom_bits"`
+
+		//number of keys between restart points for delta encoding of keys, default 16
+		BlockRestartInterval int `json:"block_restart_interval"`
+
+		//check data aggressively, stop on any corruption
+		ParanoidChecks bool `json:"paranoid_checks"`
+
+		//sync every write to disk, safe but slow
+		Sync bool `json:"sync"`
 	} `json:"db"`
 
 	BinLog struct {
 		Use         bool `json:"use"`
 		MaxFileSize int  `json:"max_file_size"`
 		MaxFileNum  int  `json:"max_file_num"`
+
+		//purge the log files last modified more than n seconds ago, 0 means no limit
+		MaxAge int `json:"max_age"`
+
+		//purge the oldest log files when the total size exceeds n bytes, 0 means no limit
+		MaxTotalSize int `json:"max_total_size"`
+
+		//when to fsync binlog, always (every commit), everysec (in background) or no (by OS), default everysec
+		Fsync string `json:"fsync"`
 	} `json:"binlog"`
 
 	//set slaveof to enable replication from master
@@ -31,6 +66,38 @@ type Config struct {
 	SlaveOf string `json:"slaveof"`
 
 	AccessLog string `json:"access_log"`
+
+	//log commands slower than n milliseconds, 0 means disabled
+	SlowLogThreshold int `json:"slowlog_threshold"`
+
+	//how many times per second to retire expired keys, default 1
+	ExpireHz int `json:"expire_hz"`
+
+	//max number of connected clients, 0 means no limit
+	MaxClients int `json:"maxclients"`
+
+	//close a client connection after it is idle for n seconds, 0 means never
+	IdleTimeout int `json:"idle_timeout"`
+
+	//seconds to wait running commands to finish when shutdown, default 10
+	ShutdownTimeout int `json:"shutdown_timeout"`
+
+	//a write command replies after so many slaves ack its binlog position, 0 means no wait
+	MinSlavesToAck int `json:"min_slaves_to_ack"`
+
+	//milliseconds to wait slaves to ack a write, default 10000,
+	//the write is still done if timeout, but replies an error
+	MinSlavesAckTimeout int `json:"min_slaves_ack_timeout"`
+
+	//max number of fullsyncs sending dumps at the same time, the others wait, default 2,
+	//the concurrent fullsyncs share the same snapshot
+	MaxParallelFullSyncs int `json:"max_parallel_fullsyncs"`
+
+	//reject write commands on a slave with READONLY error, default true
+	SlaveReadOnly *bool `json:"slave_read_only"`
+
+	//config file loaded from, used by config rewrite
+	FileName string `json:"-"`
 }
 
 func NewConfig(data json.RawMessage) (*Config, error) {
@@ -50,7 +117,32 @@ func NewConfigWithFile(fileName string) (*Config, error) {
 		return nil, err
 	}
 
-	return NewConfig(data)
+	cfg, err := NewConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.FileName = fileName
+	return cfg, nil
+}
+
+//saves config to the file loaded from
+func (cfg *Config) Rewrite() error {
+	if len(cfg.FileName) == 0 {
+		return fmt.Errorf("no config file to rewrite")
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	bakName := fmt.Sprintf("%s.bak", cfg.FileName)
+	if err = ioutil.WriteFile(bakName, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(bakName, cfg.FileName)
 }
 
 func (cfg *Config) NewLedisConfig() *ledis.Config {
@@ -61,5 +153,54 @@ func (cfg *Config) NewLedisConfig() *ledis.Config {
 	copier.Copy(&c.DB, &cfg.DB)
 	copier.Copy(&c.BinLog, &cfg.BinLog)
 
+	c.ExpireHz = cfg.ExpireHz
+
 	return c
 }
+
+//adjust sets the defaults which can not be told from the zero values
+func (cfg *Config) adjust() {
+	if cfg.SlaveReadOnly == nil {
+		readOnly := true
+		cfg.SlaveReadOnly = &readOnly
+	}
+}
+
+func (cfg *Config) minSlavesAckTimeout() time.Duration {
+	if cfg.MinSlavesAckTimeout <= 0 {
+		return defaultMinSlavesAckTimeout * time.Millisecond
+	}
+	return time.Duration(cfg.MinSlavesAckTimeout) * time.Millisecond
+}
+
+func (cfg *Config) maxParallelFullSyncs() int {
+	if cfg.MaxParallelFullSyncs <= 0 {
+		return defaultMaxParallelFullSyncs
+	}
+	return cfg.MaxParallelFullSyncs
+}
+
+//relative access log path is under data_dir
+func (cfg *Config) accessLogPath() string {
+	if len(cfg.AccessLog) > 0 && path.Dir(cfg.AccessLog) == "." {
+		return path.Join(cfg.DataDir, cfg.AccessLog)
+	}
+	return cfg.AccessLog
+}
+
+//backupPath returns the backup dir under data_dir/backup,
+//the client can not write files out of it.
+func (cfg *Config) backupPath(name string) (string, error) {
+	name = path.Clean(name)
+	if path.IsAbs(name) || name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", ErrBackupPath
+	}
+	return path.Join(cfg.DataDir, "backup", name), nil
+}
+
+func (cfg *Config) shutdownTimeout() time.Duration {
+	if cfg.ShutdownTimeout <= 0 {
+		return defaultShutdownTimeout * time.Second
+	}
+	return time.Duration(cfg.ShutdownTimeout) * time.Second
+}
@@ -8,6 +8,13 @@ var (
 	ErrEmptyCommand = errors.New("empty command")
 	ErrNotFound     = errors.New("command not found")
 	ErrCmdParams    = errors.New("invalid command param")
+	ErrMaxClients   = errors.New("max number of clients reached")
+	ErrShutdown     = errors.New("server is shutting down")
+	ErrNoSlavesAck  = errors.New("not enough slaves acked the write in time")
+	ErrBackupPath   = errors.New("backup path must be a relative path in data_dir/backup")
+
+	//replied as the error code READONLY instead of ERR
+	ErrReadOnly = errors.New("READONLY can not write against a read only slave")
 )
 
 var (
@@ -10,6 +10,7 @@ import (
 	"github.com/siddontang/go-log/log"
 	"github.com/siddontang/go-snappy/snappy"
 	"ledis"
+	"io"
 	"io/ioutil"
 	"net"
 	"os"
@@ -27,6 +28,12 @@ type MasterInfo struct {
 	Addr         string `json:"addr"`
 	LogFileIndex int64  `json:"log_file_index"`
 	LogPos       int64  `json:"log_pos"`
+
+	//the checkpoint of a broken fullsync, the data until the key is loaded,
+	//the binlog position is of the dump when the fullsync started.
+	FullSyncKey          []byte `json:"fullsync_key,omitempty"`
+	FullSyncLogFileIndex int64  `json:"fullsync_log_file_index,omitempty"`
+	FullSyncLogPos       int64  `json:"fullsync_log_pos,omitempty"`
 }
 
 func (m *MasterInfo) Save(filePath string) error {
@@ -69,11 +76,32 @@ func (m *MasterInfo) Load(filePath string) error {
 	return nil
 }
 
+//the replication states of a slave, named as redis
+const (
+	replStateNone      = ""
+	replStateConnect   = "connect"
+	replStateSync      = "sync"
+	replStateConnected = "connected"
+)
+
+//replStatus is the replication status of a slave shown in role and info
+type replStatus struct {
+	masterAddr string
+	state      string
+
+	//the last time the data from master was applied
+	lastSyncTime time.Time
+
+	logFileIndex int64
+	logPos       int64
+}
+
 type master struct {
 	sync.Mutex
 
-	c  net.Conn
-	rb *bufio.Reader
+	connLock sync.Mutex
+	c        net.Conn
+	rb       *bufio.Reader
 
 	app *App
 
@@ -88,6 +116,9 @@ type master struct {
 	syncBuf bytes.Buffer
 
 	compressBuf []byte
+
+	statusLock sync.Mutex
+	status     replStatus
 }
 
 func newMaster(app *App) *master {
@@ -114,12 +145,19 @@ func (m *master) Close() {
 	default:
 	}
 
+	//close the conn to unblock the replication goroutine, but keep m.c
+	//until it has exited, it may still be writing an ack
+	m.connLock.Lock()
 	if m.c != nil {
 		m.c.Close()
-		m.c = nil
 	}
+	m.connLock.Unlock()
 
 	m.wg.Wait()
+
+	m.connLock.Lock()
+	m.c = nil
+	m.connLock.Unlock()
 }
 
 func (m *master) loadInfo() error {
@@ -135,6 +173,9 @@ func (m *master) connect() error {
 		return fmt.Errorf("no assign master addr")
 	}
 
+	m.connLock.Lock()
+	defer m.connLock.Unlock()
+
 	if m.c != nil {
 		m.c.Close()
 		m.c = nil
@@ -154,10 +195,42 @@ func (m *master) resetInfo(addr string) {
 	m.info.Addr = addr
 	m.info.LogFileIndex = 0
 	m.info.LogPos = 0
+	m.resetFullSync()
+}
+
+func (m *master) resetFullSync() {
+	m.info.FullSyncKey = nil
+	m.info.FullSyncLogFileIndex = 0
+	m.info.FullSyncLogPos = 0
+}
+
+func (m *master) currentStatus() replStatus {
+	m.statusLock.Lock()
+	defer m.statusLock.Unlock()
+	return m.status
+}
+
+func (m *master) setReplState(state string) {
+	m.statusLock.Lock()
+	m.status.state = state
+	if state == replStateNone {
+		m.status.masterAddr = ""
+	}
+	m.statusLock.Unlock()
+}
+
+//synced records the position applied from master
+func (m *master) synced() {
+	m.statusLock.Lock()
+	m.status.lastSyncTime = time.Now()
+	m.status.logFileIndex = m.info.LogFileIndex
+	m.status.logPos = m.info.LogPos
+	m.statusLock.Unlock()
 }
 
 func (m *master) stopReplication() error {
 	m.Close()
+	m.setReplState(replStateNone)
 
 	if err := m.saveInfo(); err != nil {
 		log.Error("save master info error %s", err.Error())
@@ -181,6 +254,10 @@ func (m *master) startReplication(masterAddr string) error {
 
 	m.quit = make(chan struct{}, 1)
 
+	m.statusLock.Lock()
+	m.status = replStatus{masterAddr: masterAddr, state: replStateConnect}
+	m.statusLock.Unlock()
+
 	go m.runReplication()
 	return nil
 }
@@ -189,11 +266,21 @@ func (m *master) runReplication() {
 	m.wg.Add(1)
 	defer m.wg.Done()
 
+	defer func() {
+		//the link is down if replication is not stopped by slaveof no one
+		m.statusLock.Lock()
+		if m.status.state != replStateNone {
+			m.status.state = replStateConnect
+		}
+		m.statusLock.Unlock()
+	}()
+
 	for {
 		select {
 		case <-m.quit:
 			return
 		default:
+			m.setReplState(replStateConnect)
 			if err := m.connect(); err != nil {
 				log.Error("connect master %s error %s, try 2s later", m.info.Addr, err.Error())
 				time.Sleep(2 * time.Second)
@@ -203,9 +290,16 @@ func (m *master) runReplication() {
 
 		if m.info.LogFileIndex == 0 {
 			//try a fullsync
+			m.setReplState(replStateSync)
 			if err := m.fullSync(); err != nil {
-				log.Warn("full sync error %s", err.Error())
-				return
+				log.Warn("full sync error %s, retry 2s later", err.Error())
+
+				select {
+				case <-m.quit:
+					return
+				case <-time.After(2 * time.Second):
+				}
+				continue
 			}
 
 			if m.info.LogFileIndex == 0 {
@@ -215,86 +309,212 @@ func (m *master) runReplication() {
 			}
 		}
 
-		for {
-			for {
-				lastIndex := m.info.LogFileIndex
-				lastPos := m.info.LogPos
-				if err := m.sync(); err != nil {
-					log.Warn("sync error %s", err.Error())
-					return
-				}
-
-				if m.info.LogFileIndex == lastIndex && m.info.LogPos == lastPos {
-					//sync no data, wait 1s and retry
-					break
-				}
-			}
+		err := m.streamSync()
+		if err == errMasterNoBinLog {
+			log.Error("master %s not support binlog now, stop replication", m.info.Addr)
+			m.saveInfo()
+			return
+		} else if _, ok := err.(replyError); ok {
+			//the master does not support psync
+			log.Info("master %s not support psync, use sync instead: %s", m.info.Addr, err.Error())
+			m.c.SetReadDeadline(time.Time{})
+			m.setReplState(replStateConnected)
+			m.pollSync()
+			return
+		} else if err != nil {
+			log.Warn("psync error %s, reconnect 2s later", err.Error())
 
 			select {
 			case <-m.quit:
 				return
-			case <-time.After(1 * time.Second):
-				break
+			case <-time.After(2 * time.Second):
 			}
 		}
 	}
+}
+
+//pollSync syncs by requesting the new events every second
+func (m *master) pollSync() {
+	for {
+		for {
+			lastIndex := m.info.LogFileIndex
+			lastPos := m.info.LogPos
+			if err := m.sync(); err != nil {
+				log.Warn("sync error %s", err.Error())
+				return
+			}
+
+			if m.info.LogFileIndex == lastIndex && m.info.LogPos == lastPos {
+				//sync no data, wait 1s and retry
+				break
+			}
+		}
 
-	return
+		select {
+		case <-m.quit:
+			return
+		case <-time.After(1 * time.Second):
+			break
+		}
+	}
 }
 
 var (
-	fullSyncCmd   = []byte("*1\r\n$8\r\nfullsync\r\n")               //fullsync
-	syncCmdFormat = "*3\r\n$4\r\nsync\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n" //sync index pos
+	fullSyncCmd             = []byte("*1\r\n$8\r\nfullsync\r\n")                //fullsync
+	fullSyncResumeCmdFormat = "*2\r\n$8\r\nfullsync\r\n$%d\r\n%s\r\n"           //fullsync key
+	syncCmdFormat           = "*3\r\n$4\r\nsync\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n"  //sync index pos
+	psyncCmdFormat          = "*3\r\n$5\r\npsync\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n" //psync index pos
+	ackCmdFormat            = "*3\r\n$3\r\nack\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n"   //ack index pos
 )
 
+var errMasterNoBinLog = errors.New("master not support binlog")
+
+func formatSyncCmd(format string, logIndex int64, logPos int64) []byte {
+	logIndexStr := strconv.FormatInt(logIndex, 10)
+	logPosStr := strconv.FormatInt(logPos, 10)
+
+	return ledis.Slice(fmt.Sprintf(format, len(logIndexStr),
+		logIndexStr, len(logPosStr), logPosStr))
+}
+
+//fullSync loads the dump from master as it is received,
+//a broken fullsync is resumed from the checkpoint if the master has binlog.
 func (m *master) fullSync() error {
-	if _, err := m.c.Write(fullSyncCmd); err != nil {
+	resume := len(m.info.FullSyncKey) > 0 && m.info.FullSyncLogFileIndex > 0
+
+	cmd := fullSyncCmd
+	if resume {
+		cmd = ledis.Slice(fmt.Sprintf(fullSyncResumeCmdFormat, len(m.info.FullSyncKey), m.info.FullSyncKey))
+	}
+
+	if _, err := m.c.Write(cmd); err != nil {
 		return err
 	}
 
-	dumpPath := path.Join(m.app.cfg.DataDir, "master.dump")
-	f, err := os.OpenFile(dumpPath, os.O_CREATE|os.O_WRONLY, os.ModePerm)
+	r, err := NewFullSyncReader(m.rb)
 	if err != nil {
 		return err
 	}
 
-	defer os.Remove(dumpPath)
+	if !resume || !r.Stream {
+		m.resetFullSync()
+		if err = m.app.ldb.FlushAll(); err != nil {
+			return err
+		}
+	} else {
+		log.Info("resume full sync after key %q", m.info.FullSyncKey)
+	}
 
-	err = ReadBulkTo(m.rb, f)
-	f.Close()
+	head, err := m.app.ldb.LoadDumpWithOptions(r, &ledis.LoadDumpOptions{Checkpoint: m.fullSyncCheckpoint})
 	if err != nil {
-		log.Error("read dump data error %s", err.Error())
+		log.Error("load dump error %s", err.Error())
+
+		if err == ledis.ErrDumpCorrupted {
+			//can not resume from the corrupted data, and the loaded part may be corrupted too
+			if e := m.app.ldb.FlushAll(); e != nil {
+				log.Error("flush corrupted full sync data error %s", e.Error())
+			}
+			m.resetFullSync()
+			m.saveInfo()
+		}
 		return err
 	}
 
-	if err = m.app.ldb.FlushAll(); err != nil {
+	//read the end of reply after the dump
+	if _, err = io.Copy(ioutil.Discard, r); err != nil {
 		return err
 	}
 
-	var head *ledis.MasterInfo
-	head, err = m.app.ldb.LoadDumpFile(dumpPath)
+	if m.info.FullSyncLogFileIndex > 0 {
+		//replicate from the position of the first dump, the data loaded after is consistent then
+		m.info.LogFileIndex = m.info.FullSyncLogFileIndex
+		m.info.LogPos = m.info.FullSyncLogPos
+	} else {
+		m.info.LogFileIndex = head.LogFileIndex
+		m.info.LogPos = head.LogPos
+	}
+	m.resetFullSync()
 
-	if err != nil {
-		log.Error("load dump file error %s", err.Error())
+	if err = m.saveInfo(); err != nil {
 		return err
 	}
 
-	m.info.LogFileIndex = head.LogFileIndex
-	m.info.LogPos = head.LogPos
+	m.synced()
+	return nil
+}
+
+//fullSyncCheckpoint saves the last key loaded of fullsync
+func (m *master) fullSyncCheckpoint(info *ledis.MasterInfo, lastKey []byte) error {
+	if len(m.info.FullSyncKey) == 0 {
+		m.info.FullSyncLogFileIndex = info.LogFileIndex
+		m.info.FullSyncLogPos = info.LogPos
+	}
 
+	m.info.FullSyncKey = append(m.info.FullSyncKey[0:0], lastKey...)
 	return m.saveInfo()
 }
 
 func (m *master) sync() error {
-	logIndexStr := strconv.FormatInt(m.info.LogFileIndex, 10)
-	logPosStr := strconv.FormatInt(m.info.LogPos, 10)
+	cmd := formatSyncCmd(syncCmdFormat, m.info.LogFileIndex, m.info.LogPos)
+	if _, err := m.c.Write(cmd); err != nil {
+		return err
+	}
 
-	cmd := ledis.Slice(fmt.Sprintf(syncCmdFormat, len(logIndexStr),
-		logIndexStr, len(logPosStr), logPosStr))
+	if err := m.readSyncData(); err != nil {
+		return err
+	}
+
+	if m.info.LogFileIndex == 0 {
+		//master now not support binlog, stop replication
+		m.stopReplication()
+		return nil
+	} else if m.info.LogFileIndex == -1 {
+		//-1 means than binlog index and pos are lost, we must start a full sync instead
+		return m.fullSync()
+	}
+
+	return nil
+}
+
+//streamSync applies the events pushed by the master and acks the position,
+//returns nil if a fullsync is needed.
+func (m *master) streamSync() error {
+	cmd := formatSyncCmd(psyncCmdFormat, m.info.LogFileIndex, m.info.LogPos)
 	if _, err := m.c.Write(cmd); err != nil {
 		return err
 	}
 
+	m.setReplState(replStateConnected)
+
+	for {
+		//the master sends the position at least every heartbeat
+		m.c.SetReadDeadline(time.Now().Add(replTimeout))
+
+		if err := m.readSyncData(); err != nil {
+			return err
+		}
+
+		switch m.info.LogFileIndex {
+		case 0:
+			return errMasterNoBinLog
+		case -1:
+			//binlog index and pos are lost, fullsync after reconnecting
+			m.info.LogFileIndex = 0
+			return nil
+		}
+
+		cmd = formatSyncCmd(ackCmdFormat, m.info.LogFileIndex, m.info.LogPos)
+		if _, err := m.c.Write(cmd); err != nil {
+			return err
+		}
+	}
+}
+
+//test hook, called with the events before they are applied
+var syncDataFailpoint func(data []byte) error
+
+//readSyncData reads the data of sync or psync, applies the events and saves the next position.
+func (m *master) readSyncData() error {
 	m.syncBuf.Reset()
 
 	err := ReadBulkTo(m.rb, &m.syncBuf)
@@ -314,25 +534,37 @@ func (m *master) sync() error {
 		return fmt.Errorf("invalid sync data len %d", len(buf))
 	}
 
-	m.info.LogFileIndex = int64(binary.BigEndian.Uint64(buf[0:8]))
-	m.info.LogPos = int64(binary.BigEndian.Uint64(buf[8:16]))
+	logIndex := int64(binary.BigEndian.Uint64(buf[0:8]))
+	logPos := int64(binary.BigEndian.Uint64(buf[8:16]))
 
-	if m.info.LogFileIndex == 0 {
-		//master now not support binlog, stop replication
-		m.stopReplication()
+	if logIndex <= 0 {
+		m.info.LogFileIndex = logIndex
+		m.info.LogPos = logPos
 		return nil
-	} else if m.info.LogFileIndex == -1 {
-		//-1 means than binlog index and pos are lost, we must start a full sync instead
-		return m.fullSync()
 	}
 
+	if syncDataFailpoint != nil {
+		if err = syncDataFailpoint(buf[16:]); err != nil {
+			return err
+		}
+	}
+
+	//the position is moved only after the events are applied,
+	//so they are synced again after reconnecting if failed
 	err = m.app.ldb.ReplicateFromData(buf[16:])
 	if err != nil {
 		return err
 	}
 
-	return m.saveInfo()
+	m.info.LogFileIndex = logIndex
+	m.info.LogPos = logPos
 
+	if err = m.saveInfo(); err != nil {
+		return err
+	}
+
+	m.synced()
+	return nil
 }
 
 func (app *App) slaveof(masterAddr string) error {
@@ -27,10 +27,21 @@ func ReadLine(rb *bufio.Reader) ([]byte, error) {
 	return p[:i], nil
 }
 
+//replyError is an error reply, like "-ERR command not found"
+type replyError string
+
+func (e replyError) Error() string {
+	return string(e)
+}
+
 func ReadBulkTo(rb *bufio.Reader, w io.Writer) error {
 	l, err := ReadLine(rb)
-	if len(l) == 0 {
+	if err != nil {
+		return err
+	} else if len(l) == 0 {
 		return errBulkFormat
+	} else if l[0] == '-' {
+		return replyError(l[1:])
 	} else if l[0] == '$' {
 		var n int
 		//handle resp string
@@ -55,3 +66,122 @@ func ReadBulkTo(rb *bufio.Reader, w io.Writer) error {
 
 	return nil
 }
+
+//FullSyncReader reads the dump replied by fullsync,
+//the dump is sent in bulk chunks after status FULLSYNC and ends with a null bulk,
+//or in a single bulk by the old versions.
+type FullSyncReader struct {
+	rb *bufio.Reader
+
+	//false if the dump is in a single bulk, it can not be resumed
+	Stream bool
+
+	//the bytes left in current chunk
+	n   int64
+	eof bool
+}
+
+//NewFullSyncReader reads the status of fullsync reply
+func NewFullSyncReader(rb *bufio.Reader) (*FullSyncReader, error) {
+	b, err := rb.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &FullSyncReader{rb: rb}
+
+	switch b[0] {
+	case '+':
+		if _, err = ReadLine(rb); err != nil {
+			return nil, err
+		}
+		r.Stream = true
+	case '$':
+		//the size line is read as the only chunk
+	case '-':
+		l, err := ReadLine(rb)
+		if err != nil {
+			return nil, err
+		}
+		return nil, replyError(l[1:])
+	default:
+		return nil, errBulkFormat
+	}
+
+	return r, nil
+}
+
+func (r *FullSyncReader) Read(p []byte) (int, error) {
+	for r.n == 0 {
+		if r.eof {
+			return 0, io.EOF
+		} else if err := r.nextChunk(); err != nil {
+			return 0, err
+		}
+	}
+
+	if int64(len(p)) > r.n {
+		p = p[0:r.n]
+	}
+
+	n, err := r.rb.Read(p)
+	r.n -= int64(n)
+	if err == io.EOF {
+		return n, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return n, err
+	}
+
+	if r.n == 0 {
+		if l, err := ReadLine(r.rb); err != nil {
+			return n, err
+		} else if len(l) != 0 {
+			return n, errBulkFormat
+		}
+
+		r.eof = !r.Stream
+	}
+
+	return n, nil
+}
+
+func (r *FullSyncReader) nextChunk() error {
+	l, err := ReadLine(r.rb)
+	if err == io.EOF {
+		//the end of dump is lost
+		return io.ErrUnexpectedEOF
+	} else if err != nil {
+		return err
+	} else if len(l) == 0 {
+		return errBulkFormat
+	} else if l[0] == '-' {
+		//the master failed to dump
+		return replyError(l[1:])
+	} else if l[0] != '$' {
+		return errBulkFormat
+	}
+
+	n, err := strconv.ParseInt(ledis.String(l[1:]), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case n == -1:
+		r.eof = true
+	case n == 0:
+		//an empty chunk
+		if l, err = ReadLine(r.rb); err != nil {
+			return err
+		} else if len(l) != 0 {
+			return errBulkFormat
+		}
+		r.eof = !r.Stream
+	case n > 0:
+		r.n = n
+	default:
+		return errBulkFormat
+	}
+
+	return nil
+}
@@ -0,0 +1,9 @@
+package ledis
+
+import (
+	//the default pure go store driver
+	_ "store/goleveldb"
+
+	//the in-memory store driver, for tests and caches
+	_ "store/memory"
+)
@@ -0,0 +1,8 @@
+// +build leveldb
+
+package ledis
+
+import (
+	//the c++ leveldb store driver, build with -tags leveldb
+	_ "leveldb"
+)
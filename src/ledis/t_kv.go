@@ -2,7 +2,7 @@ package ledis
 
 import (
 	"errors"
-	"leveldb"
+	"store"
 	"time"
 )
 
@@ -343,9 +343,9 @@ func (db *DB) Scan(key []byte, count int, inclusive bool) ([]KVPair, error) {
 
 	v := make([]KVPair, 0, 2*count)
 
-	rangeType := leveldb.RangeROpen
+	rangeType := store.RangeROpen
 	if !inclusive {
-		rangeType = leveldb.RangeOpen
+		rangeType = store.RangeOpen
 	}
 
 	it := db.db.RangeLimitIterator(minKey, maxKey, rangeType, 0, count)
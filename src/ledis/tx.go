@@ -1,15 +1,20 @@
 package ledis
 
 import (
+	"github.com/siddontang/go-log/log"
+	"store"
 	"sync"
-	"leveldb"
 )
 
+//binLogEventIDKey stores the id of the last binlog event committed with the data,
+//it is out of all dbs, so it is not dumped or flushed.
+var binLogEventIDKey = []byte("\xffbinlog_event_id")
+
 type tx struct {
 	m sync.Mutex
 
 	l  *Ledis
-	wb *leveldb.WriteBatch
+	wb *store.WriteBatch
 
 	binlog *BinLog
 	batch  [][]byte
@@ -59,26 +64,109 @@ func (t *tx) Unlock() {
 }
 
 func (t *tx) Commit() error {
-	var err error
-	if t.binlog != nil {
-		t.l.Lock()
-		err = t.wb.Commit()
-		if err != nil {
-			t.l.Unlock()
-			return err
-		}
-
-		err = t.binlog.Log(t.batch...)
-
-		t.l.Unlock()
-	} else {
-		t.l.Lock()
-		err = t.wb.Commit()
-		t.l.Unlock()
-	}
+	t.l.Lock()
+	err := t.l.commitWithLog(t.wb, t.batch)
+	t.l.Unlock()
 	return err
 }
 
 func (t *tx) Rollback() {
 	t.wb.Rollback()
 }
+
+//commitWithLog logs the events to binlog first, then commits the batch with the last event id,
+//if crashed after logging, the events are committed by recoverBinLog when opened again,
+//if the batch fails to commit, the events are removed from binlog.
+//it must be called with the ledis lock.
+func (l *Ledis) commitWithLog(wb *store.WriteBatch, events [][]byte) error {
+	if l.binlog == nil || len(events) == 0 {
+		return wb.Commit()
+	}
+
+	if err := l.binlog.Log(events...); err != nil {
+		return err
+	}
+
+	wb.Put(binLogEventIDKey, PutInt64(int64(l.binlog.LastEventID())))
+
+	if err := wb.Commit(); err != nil {
+		if e := l.binlog.undoLast(); e != nil {
+			log.Error("undo binlog for failed commit error %s", e.Error())
+		}
+		return err
+	}
+
+	l.notifyBinLog()
+	return nil
+}
+
+//recoverBinLog commits the events logged but not committed with data when crashed.
+func (l *Ledis) recoverBinLog() error {
+	v, err := l.ldb.Get(binLogEventIDKey)
+	if err != nil {
+		return err
+	}
+
+	lastID := l.binlog.LastEventID()
+
+	if v == nil {
+		//data committed before the event id is stored, start with current binlog
+		return l.ldb.Put(binLogEventIDKey, PutInt64(int64(lastID)))
+	}
+
+	n, err := Int64(v, nil)
+	if err != nil {
+		return err
+	}
+
+	id := uint64(n)
+	if id > lastID {
+		//the binlog is purged or removed, the later event ids go on with data
+		l.binlog.setLastEventID(id)
+		return nil
+	} else if id == lastID {
+		return nil
+	}
+
+	wb := l.ldb.NewWriteBatch()
+	defer wb.Close()
+
+	next := id + 1
+	err = l.binlog.readEventsAfter(id, func(e *BinLogEvent) error {
+		if e.ID != next {
+			log.Error("binlog event %d-%d lost, can not be recovered", next, e.ID-1)
+		}
+		next = e.ID + 1
+
+		if len(e.Payload) == 0 {
+			return errInvalidBinLogEvent
+		}
+
+		switch e.Payload[0] {
+		case BinLogTypePut:
+			key, value, err := decodeBinLogPut(e.Payload)
+			if err != nil {
+				return err
+			}
+			wb.Put(key, value)
+		case BinLogTypeDeletion:
+			key, err := decodeBinLogDelete(e.Payload)
+			if err != nil {
+				return err
+			}
+			wb.Delete(key)
+		default:
+			return errInvalidBinLogEvent
+		}
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	log.Info("recover binlog events %d-%d not committed", id+1, lastID)
+
+	wb.Put(binLogEventIDKey, PutInt64(int64(lastID)))
+	return wb.SyncCommit()
+}
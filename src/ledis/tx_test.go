@@ -0,0 +1,215 @@
+package ledis
+
+import (
+	"errors"
+	"os"
+	"path"
+	"store"
+	"testing"
+)
+
+const testTxDataDir = "/tmp/test_ledis_tx"
+
+//failCommitStore wraps the default store, its batch commits fail with commitFailure if set
+const failCommitStore = "fail_commit"
+
+var commitFailure error
+
+type failStore struct{}
+
+func (s failStore) String() string {
+	return failCommitStore
+}
+
+func (s failStore) Open(cfg *store.Config) (store.IDB, error) {
+	c := *cfg
+	c.Name = store.DefaultDriverName
+
+	db, err := store.Open(&c)
+	if err != nil {
+		return nil, err
+	}
+	return &failDB{db.Driver()}, nil
+}
+
+func (s failStore) Repair(cfg *store.Config) error {
+	c := *cfg
+	c.Name = store.DefaultDriverName
+	return store.Repair(&c)
+}
+
+type failDB struct {
+	store.IDB
+}
+
+func (db *failDB) NewWriteBatch() store.IWriteBatch {
+	return &failWriteBatch{db.IDB.NewWriteBatch()}
+}
+
+type failWriteBatch struct {
+	store.IWriteBatch
+}
+
+func (w *failWriteBatch) Commit() error {
+	if commitFailure != nil {
+		return commitFailure
+	}
+	return w.IWriteBatch.Commit()
+}
+
+func init() {
+	store.Register(failStore{})
+}
+
+func openTestTxLedis(t *testing.T) *Ledis {
+	cfg := new(Config)
+	cfg.DataDir = testTxDataDir
+	cfg.DB.Name = failCommitStore
+	cfg.BinLog.Use = true
+
+	l, err := Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func checkTxValue(t *testing.T, l *Ledis, key string, value string) {
+	db, _ := l.Select(0)
+	if v, err := db.Get([]byte(key)); err != nil {
+		t.Fatal(err)
+	} else if string(v) != value {
+		t.Fatalf("key %s: %q != %q", key, v, value)
+	}
+}
+
+func TestCommitFailure(t *testing.T) {
+	os.RemoveAll(testTxDataDir)
+
+	l := openTestTxLedis(t)
+	defer l.Close()
+
+	db, _ := l.Select(0)
+	if err := db.Set([]byte("a"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+
+	id := l.binlog.LastEventID()
+	pos := l.binlog.LogFilePos()
+
+	commitFailure = errors.New("injected failure")
+	defer func() {
+		commitFailure = nil
+	}()
+
+	if err := db.Set([]byte("a"), []byte("2")); err != commitFailure {
+		t.Fatal(err)
+	}
+
+	//neither data nor binlog is written
+	checkTxValue(t, l, "a", "1")
+
+	if n := l.binlog.LastEventID(); n != id {
+		t.Fatal(n, id)
+	} else if n := l.binlog.LogFilePos(); n != pos {
+		t.Fatal(n, pos)
+	}
+
+	commitFailure = nil
+
+	if err := db.Set([]byte("a"), []byte("3")); err != nil {
+		t.Fatal(err)
+	} else if n := l.binlog.LastEventID(); n != id+1 {
+		t.Fatal(n, id)
+	}
+
+	var events []uint64
+	f, err := os.Open(l.binlog.FormatLogFilePath(l.binlog.LogFileIndex()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	err = ReadBinLogEvents(f, func(e *BinLogEvent) error {
+		events = append(events, e.ID)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	} else if len(events) != 2 || events[1] != id+1 {
+		t.Fatal(events)
+	}
+}
+
+func TestCommitRecover(t *testing.T) {
+	os.RemoveAll(testTxDataDir)
+
+	l := openTestTxLedis(t)
+
+	db, _ := l.Select(0)
+	if err := db.Set([]byte("a"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+
+	//crash after logging but before committing the data
+	l.Lock()
+	err := l.binlog.Log(encodeBinLogPut(db.encodeKVKey([]byte("a")), []byte("2")),
+		encodeBinLogPut(db.encodeKVKey([]byte("b")), []byte("3")))
+	l.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkTxValue(t, l, "a", "1")
+
+	id := l.binlog.LastEventID()
+	l.Close()
+
+	//the logged events are committed when opened again
+	l = openTestTxLedis(t)
+
+	checkTxValue(t, l, "a", "2")
+	checkTxValue(t, l, "b", "3")
+
+	if n := l.binlog.LastEventID(); n != id {
+		t.Fatal(n, id)
+	}
+
+	db, _ = l.Select(0)
+	if err := db.Set([]byte("c"), []byte("4")); err != nil {
+		t.Fatal(err)
+	}
+
+	logPath := l.binlog.FormatLogFilePath(l.binlog.LogFileIndex())
+	l.Close()
+
+	//crash when writing an event, the partial event is removed
+	f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 0})
+	f.Close()
+
+	l = openTestTxLedis(t)
+
+	checkTxValue(t, l, "a", "2")
+	checkTxValue(t, l, "c", "4")
+
+	if n := l.binlog.LastEventID(); n != id+1 {
+		t.Fatal(n, id)
+	}
+
+	//the binlog is removed, the event id goes on with data
+	l.Close()
+	os.RemoveAll(path.Join(testTxDataDir, "bin_log"))
+
+	l = openTestTxLedis(t)
+
+	if n := l.binlog.LastEventID(); n != id+1 {
+		t.Fatal(n, id)
+	}
+
+	l.Close()
+}
@@ -1,8 +1,12 @@
+// +build leveldb
+
 // Package leveldb is a wrapper for c++ leveldb
 package leveldb
 
 /*
 #cgo LDFLAGS: -lleveldb
+#include <stdlib.h>
+#include <stdint.h>
 #include <leveldb/c.h>
 #include "leveldb_ext.h"
 */
@@ -15,6 +19,7 @@ import (
 )
 
 const defaultFilterBits int = 10
+const defaultBlockRestartInterval int = 16
 
 type Config struct {
 	Path string `json:"path"`
@@ -24,6 +29,18 @@ type Config struct {
 	WriteBufferSize int  `json:"write_buffer_size"`
 	CacheSize       int  `json:"cache_size"`
 	MaxOpenFiles    int  `json:"max_open_files"`
+
+	//bloom filter bits per key, default 10
+	BloomBits int `json:"bloom_bits"`
+
+	//number of keys between restart points for delta encoding of keys, default 16
+	BlockRestartInterval int `json:"block_restart_interval"`
+
+	//check data aggressively, stop on any corruption
+	ParanoidChecks bool `json:"paranoid_checks"`
+
+	//sync every write to disk, safe but slow
+	Sync bool `json:"sync"`
 }
 
 type DB struct {
@@ -121,7 +138,11 @@ func (db *DB) initOptions(cfg *Config) {
 	opts.SetCache(db.cache)
 
 	//we must use bloomfilter
-	db.filter = NewBloomFilter(defaultFilterBits)
+	if cfg.BloomBits <= 0 {
+		cfg.BloomBits = defaultFilterBits
+	}
+
+	db.filter = NewBloomFilter(cfg.BloomBits)
 	opts.SetFilterPolicy(db.filter)
 
 	if !cfg.Compression {
@@ -134,6 +155,14 @@ func (db *DB) initOptions(cfg *Config) {
 
 	opts.SetBlockSize(cfg.BlockSize)
 
+	if cfg.BlockRestartInterval <= 0 {
+		cfg.BlockRestartInterval = defaultBlockRestartInterval
+	}
+
+	opts.SetBlockRestartInterval(cfg.BlockRestartInterval)
+
+	opts.SetParanoidChecks(cfg.ParanoidChecks)
+
 	if cfg.WriteBufferSize <= 0 {
 		cfg.WriteBufferSize = 4 * 1024 * 1024
 	}
@@ -150,6 +179,7 @@ func (db *DB) initOptions(cfg *Config) {
 
 	db.readOpts = NewReadOptions()
 	db.writeOpts = NewWriteOptions()
+	db.writeOpts.SetSync(cfg.Sync)
 
 	db.iteratorOpts = NewReadOptions()
 	db.iteratorOpts.SetFillCache(false)
@@ -303,6 +333,80 @@ func (db *DB) RevRangeLimitIterator(min []byte, max []byte, rangeType uint8, off
 	return NewRevRangeLimitIterator(db.NewIterator(), &Range{min, max, rangeType}, &Limit{offset, count})
 }
 
+//CompactRange compacts the key range between start and limit,
+//nil start means the first key, nil limit means the last key.
+func (db *DB) CompactRange(start []byte, limit []byte) error {
+	var s, l *C.char
+	if len(start) != 0 {
+		s = (*C.char)(unsafe.Pointer(&start[0]))
+	}
+	if len(limit) != 0 {
+		l = (*C.char)(unsafe.Pointer(&limit[0]))
+	}
+
+	C.leveldb_compact_range(
+		db.db, s, C.size_t(len(start)), l, C.size_t(len(limit)))
+
+	return nil
+}
+
+//SizeRange is the key range [Start, Limit) for ApproximateSizes.
+type SizeRange struct {
+	Start []byte
+	Limit []byte
+}
+
+//ApproximateSizes returns the approximate file system space used by the keys in each range,
+//the recently written data may not be counted until they are compacted to disk.
+func (db *DB) ApproximateSizes(ranges []SizeRange) []uint64 {
+	n := len(ranges)
+	sizes := make([]uint64, n)
+	if n == 0 {
+		return sizes
+	}
+
+	//keys are copied to c memory, go memory can not be passed to c in a pointer array
+	starts := make([]*C.char, n)
+	startLens := make([]C.size_t, n)
+	limits := make([]*C.char, n)
+	limitLens := make([]C.size_t, n)
+
+	for i, r := range ranges {
+		starts[i] = C.CString(string(r.Start))
+		startLens[i] = C.size_t(len(r.Start))
+		limits[i] = C.CString(string(r.Limit))
+		limitLens[i] = C.size_t(len(r.Limit))
+	}
+
+	defer func() {
+		for i := 0; i < n; i++ {
+			C.free(unsafe.Pointer(starts[i]))
+			C.free(unsafe.Pointer(limits[i]))
+		}
+	}()
+
+	C.leveldb_approximate_sizes(db.db, C.int(n),
+		&starts[0], &startLens[0], &limits[0], &limitLens[0],
+		(*C.uint64_t)(unsafe.Pointer(&sizes[0])))
+
+	return sizes
+}
+
+//PropertyValue returns the property of the db, like "leveldb.stats",
+//empty string returns if the property does not exist.
+func (db *DB) PropertyValue(name string) string {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	v := C.leveldb_property_value(db.db, cname)
+	if v == nil {
+		return ""
+	}
+	defer C.leveldb_free(unsafe.Pointer(v))
+
+	return C.GoString(v)
+}
+
 func (db *DB) put(wo *WriteOptions, key, value []byte) error {
 	var errStr *C.char
 	var k, v *C.char
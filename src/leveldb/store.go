@@ -0,0 +1,122 @@
+// +build leveldb
+
+package leveldb
+
+import (
+	"fmt"
+	"store"
+)
+
+//the store driver for c++ leveldb, import it to register:
+//
+//     import _ "leveldb"
+//
+const DriverName = "leveldb"
+
+type Store struct {
+}
+
+func (s Store) String() string {
+	return DriverName
+}
+
+func (s Store) Open(cfg *store.Config) (store.IDB, error) {
+	db, err := Open(newConfig(cfg))
+	if err != nil {
+		return nil, err
+	}
+
+	return &storeDB{db}, nil
+}
+
+func (s Store) Repair(cfg *store.Config) error {
+	return Repair(newConfig(cfg))
+}
+
+func newConfig(cfg *store.Config) *Config {
+	c := new(Config)
+
+	c.Path = cfg.Path
+	c.Compression = cfg.Compression
+	c.BlockSize = cfg.BlockSize
+	c.WriteBufferSize = cfg.WriteBufferSize
+	c.CacheSize = cfg.CacheSize
+	c.MaxOpenFiles = cfg.MaxOpenFiles
+	c.BloomBits = cfg.BloomBits
+	c.BlockRestartInterval = cfg.BlockRestartInterval
+	c.ParanoidChecks = cfg.ParanoidChecks
+	c.Sync = cfg.Sync
+
+	return c
+}
+
+type storeDB struct {
+	*DB
+}
+
+func (db *storeDB) NewIterator() store.IIterator {
+	return &storeIterator{db.DB.NewIterator()}
+}
+
+func (db *storeDB) NewWriteBatch() store.IWriteBatch {
+	return db.DB.NewWriteBatch()
+}
+
+func (db *storeDB) NewSnapshot() (store.ISnapshot, error) {
+	return &storeSnapshot{db.DB.NewSnapshot()}, nil
+}
+
+func (db *storeDB) SizeOf(ranges []store.KeyRange) ([]int64, error) {
+	rs := make([]SizeRange, len(ranges))
+	for i, r := range ranges {
+		rs[i] = SizeRange{r.Start, r.Limit}
+	}
+
+	sizes := db.DB.ApproximateSizes(rs)
+
+	s := make([]int64, len(sizes))
+	for i, size := range sizes {
+		s[i] = int64(size)
+	}
+	return s, nil
+}
+
+func (db *storeDB) GetProperty(name string) (string, error) {
+	v := db.DB.PropertyValue(name)
+	if len(v) == 0 {
+		return "", fmt.Errorf("leveldb: unknown property %s", name)
+	}
+	return v, nil
+}
+
+type storeSnapshot struct {
+	*Snapshot
+}
+
+func (s *storeSnapshot) NewIterator() store.IIterator {
+	return &storeIterator{s.Snapshot.NewIterator()}
+}
+
+type storeIterator struct {
+	*Iterator
+}
+
+func (it *storeIterator) First() {
+	it.SeekToFirst()
+}
+
+func (it *storeIterator) Last() {
+	it.SeekToLast()
+}
+
+func (it *storeIterator) Key() []byte {
+	return it.RawKey()
+}
+
+func (it *storeIterator) Value() []byte {
+	return it.RawValue()
+}
+
+func init() {
+	store.Register(Store{})
+}
@@ -0,0 +1,88 @@
+package store
+
+type DB struct {
+	cfg *Config
+
+	db IDB
+}
+
+func (db *DB) Config() *Config {
+	return db.cfg
+}
+
+//Driver returns the underlying driver db,
+//it can be used to check the optional driver features.
+func (db *DB) Driver() IDB {
+	return db.db
+}
+
+func (db *DB) Close() error {
+	return db.db.Close()
+}
+
+func (db *DB) Get(key []byte) ([]byte, error) {
+	return db.db.Get(key)
+}
+
+func (db *DB) Put(key []byte, value []byte) error {
+	return db.db.Put(key, value)
+}
+
+func (db *DB) Delete(key []byte) error {
+	return db.db.Delete(key)
+}
+
+//CompactRange compacts the key range between start and limit,
+//nil start means the first key, nil limit means the last key.
+func (db *DB) CompactRange(start []byte, limit []byte) error {
+	return db.db.CompactRange(start, limit)
+}
+
+//SizeOf returns the approximate storage space in bytes of each key range.
+func (db *DB) SizeOf(ranges []KeyRange) ([]int64, error) {
+	return db.db.SizeOf(ranges)
+}
+
+//GetProperty returns the driver property, like "leveldb.stats".
+func (db *DB) GetProperty(name string) (string, error) {
+	return db.db.GetProperty(name)
+}
+
+func (db *DB) NewWriteBatch() *WriteBatch {
+	return &WriteBatch{db.db.NewWriteBatch()}
+}
+
+func (db *DB) NewSnapshot() (*Snapshot, error) {
+	s, err := db.db.NewSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{s}, nil
+}
+
+func (db *DB) NewIterator() *Iterator {
+	return &Iterator{it: db.db.NewIterator()}
+}
+
+func (db *DB) RangeIterator(min []byte, max []byte, rangeType uint8) *RangeLimitIterator {
+	return NewRangeLimitIterator(db.NewIterator(), &Range{min, max, rangeType}, &Limit{0, -1})
+}
+
+func (db *DB) RevRangeIterator(min []byte, max []byte, rangeType uint8) *RangeLimitIterator {
+	return NewRevRangeLimitIterator(db.NewIterator(), &Range{min, max, rangeType}, &Limit{0, -1})
+}
+
+//count < 0, unlimit.
+//
+//offset must >= 0, if < 0, will get nothing.
+func (db *DB) RangeLimitIterator(min []byte, max []byte, rangeType uint8, offset int, count int) *RangeLimitIterator {
+	return NewRangeLimitIterator(db.NewIterator(), &Range{min, max, rangeType}, &Limit{offset, count})
+}
+
+//count < 0, unlimit.
+//
+//offset must >= 0, if < 0, will get nothing.
+func (db *DB) RevRangeLimitIterator(min []byte, max []byte, rangeType uint8, offset int, count int) *RangeLimitIterator {
+	return NewRevRangeLimitIterator(db.NewIterator(), &Range{min, max, rangeType}, &Limit{offset, count})
+}
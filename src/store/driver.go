@@ -0,0 +1,86 @@
+package store
+
+//IDB is the interface a storage driver must implement.
+type IDB interface {
+	Close() error
+
+	//Get returns nil, nil if the key does not exist.
+	Get(key []byte) ([]byte, error)
+
+	Put(key []byte, value []byte) error
+	Delete(key []byte) error
+
+	NewIterator() IIterator
+
+	NewWriteBatch() IWriteBatch
+
+	NewSnapshot() (ISnapshot, error)
+
+	//CompactRange compacts the key range between start and limit,
+	//nil start means the first key, nil limit means the last key.
+	CompactRange(start []byte, limit []byte) error
+
+	//SizeOf returns the approximate storage space in bytes of each key range.
+	SizeOf(ranges []KeyRange) ([]int64, error)
+
+	//GetProperty returns the driver property, like "leveldb.stats",
+	//an error returns if the property does not exist.
+	GetProperty(name string) (string, error)
+}
+
+//KeyRange is the key range [Start, Limit).
+type KeyRange struct {
+	Start []byte
+	Limit []byte
+}
+
+type ISnapshot interface {
+	//Get returns nil, nil if the key does not exist.
+	Get(key []byte) ([]byte, error)
+
+	NewIterator() IIterator
+
+	Close()
+}
+
+//IIterator iterates the keys in ascending byte order.
+type IIterator interface {
+	Close()
+
+	First()
+	Last()
+
+	//Seek moves to the first key which is greater than or equal to key.
+	Seek(key []byte)
+
+	Next()
+	Prev()
+
+	Valid() bool
+
+	//Key and Value return references,
+	//they may be changed after the next iterate.
+	Key() []byte
+	Value() []byte
+}
+
+type IWriteBatch interface {
+	Put(key []byte, value []byte)
+	Delete(key []byte)
+
+	Commit() error
+	SyncCommit() error
+
+	Rollback()
+
+	Close()
+}
+
+//Driver opens a store by config, registered with Register.
+type Driver interface {
+	String() string
+
+	Open(cfg *Config) (IDB, error)
+
+	Repair(cfg *Config) error
+}
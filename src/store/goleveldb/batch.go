@@ -0,0 +1,40 @@
+package goleveldb
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+type WriteBatch struct {
+	db     *DB
+	wbatch *leveldb.Batch
+}
+
+func (w *WriteBatch) Close() {
+	w.wbatch.Reset()
+}
+
+func (w *WriteBatch) Put(key, value []byte) {
+	w.wbatch.Put(key, value)
+}
+
+func (w *WriteBatch) Delete(key []byte) {
+	w.wbatch.Delete(key)
+}
+
+func (w *WriteBatch) Commit() error {
+	return w.commit(w.db.writeOpts)
+}
+
+func (w *WriteBatch) SyncCommit() error {
+	return w.commit(w.db.syncWriteOpts)
+}
+
+func (w *WriteBatch) Rollback() {
+	w.wbatch.Reset()
+}
+
+//like leveldb, the batch is not cleared after commit, use Rollback to clear it.
+func (w *WriteBatch) commit(wo *opt.WriteOptions) error {
+	return w.db.db.Write(w.wbatch, wo)
+}
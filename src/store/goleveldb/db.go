@@ -0,0 +1,197 @@
+// Package goleveldb is a pure go store driver based on goleveldb,
+// it does not need cgo and the system leveldb.
+package goleveldb
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/filter"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"store"
+)
+
+const DriverName = "goleveldb"
+
+const defaultFilterBits int = 10
+const defaultBlockRestartInterval int = 16
+
+type Store struct {
+}
+
+func (s Store) String() string {
+	return DriverName
+}
+
+func (s Store) Open(cfg *store.Config) (store.IDB, error) {
+	db := new(DB)
+	db.cfg = cfg
+
+	if err := db.open(); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func (s Store) Repair(cfg *store.Config) error {
+	db, err := leveldb.RecoverFile(cfg.Path, newOptions(cfg))
+	if err != nil {
+		return err
+	}
+
+	return db.Close()
+}
+
+type DB struct {
+	cfg *store.Config
+
+	db *leveldb.DB
+
+	iteratorOpts *opt.ReadOptions
+
+	writeOpts     *opt.WriteOptions
+	syncWriteOpts *opt.WriteOptions
+}
+
+func (db *DB) open() error {
+	var err error
+	db.db, err = leveldb.OpenFile(db.cfg.Path, newOptions(db.cfg))
+	if err != nil {
+		return err
+	}
+
+	db.iteratorOpts = &opt.ReadOptions{DontFillCache: true}
+	db.writeOpts = &opt.WriteOptions{Sync: db.cfg.Sync}
+	db.syncWriteOpts = &opt.WriteOptions{Sync: true}
+
+	return nil
+}
+
+func newOptions(cfg *store.Config) *opt.Options {
+	opts := new(opt.Options)
+
+	opts.ErrorIfMissing = false
+
+	if cfg.CacheSize <= 0 {
+		cfg.CacheSize = 4 * 1024 * 1024
+	}
+
+	opts.BlockCacheCapacity = cfg.CacheSize
+
+	//we must use bloomfilter
+	if cfg.BloomBits <= 0 {
+		cfg.BloomBits = defaultFilterBits
+	}
+
+	opts.Filter = filter.NewBloomFilter(cfg.BloomBits)
+
+	if !cfg.Compression {
+		opts.Compression = opt.NoCompression
+	} else {
+		opts.Compression = opt.SnappyCompression
+	}
+
+	if cfg.BlockSize <= 0 {
+		cfg.BlockSize = 4 * 1024
+	}
+
+	opts.BlockSize = cfg.BlockSize
+
+	if cfg.BlockRestartInterval <= 0 {
+		cfg.BlockRestartInterval = defaultBlockRestartInterval
+	}
+
+	opts.BlockRestartInterval = cfg.BlockRestartInterval
+
+	if cfg.ParanoidChecks {
+		opts.Strict = opt.StrictAll
+	}
+
+	if cfg.WriteBufferSize <= 0 {
+		cfg.WriteBufferSize = 4 * 1024 * 1024
+	}
+
+	opts.WriteBuffer = cfg.WriteBufferSize
+
+	if cfg.MaxOpenFiles < 1024 {
+		cfg.MaxOpenFiles = 1024
+	}
+
+	opts.OpenFilesCacheCapacity = cfg.MaxOpenFiles
+
+	return opts
+}
+
+func (db *DB) Close() error {
+	return db.db.Close()
+}
+
+func (db *DB) Get(key []byte) ([]byte, error) {
+	v, err := db.db.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	return v, err
+}
+
+func (db *DB) Put(key []byte, value []byte) error {
+	return db.db.Put(key, value, db.writeOpts)
+}
+
+func (db *DB) Delete(key []byte) error {
+	return db.db.Delete(key, db.writeOpts)
+}
+
+func (db *DB) CompactRange(start []byte, limit []byte) error {
+	return db.db.CompactRange(util.Range{Start: start, Limit: limit})
+}
+
+func (db *DB) SizeOf(ranges []store.KeyRange) ([]int64, error) {
+	rs := make([]util.Range, len(ranges))
+	for i, r := range ranges {
+		rs[i] = util.Range{Start: r.Start, Limit: r.Limit}
+	}
+
+	sizes, err := db.db.SizeOf(rs)
+	if err != nil {
+		return nil, err
+	}
+	return []int64(sizes), nil
+}
+
+func (db *DB) GetProperty(name string) (string, error) {
+	return db.db.GetProperty(name)
+}
+
+func (db *DB) NewWriteBatch() store.IWriteBatch {
+	wb := &WriteBatch{
+		db:     db,
+		wbatch: new(leveldb.Batch),
+	}
+	return wb
+}
+
+func (db *DB) NewSnapshot() (store.ISnapshot, error) {
+	snap, err := db.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{
+		db:   db,
+		snap: snap,
+	}
+	return s, nil
+}
+
+func (db *DB) NewIterator() store.IIterator {
+	it := &Iterator{
+		db.db.NewIterator(nil, db.iteratorOpts),
+	}
+
+	return it
+}
+
+func init() {
+	store.Register(Store{})
+}
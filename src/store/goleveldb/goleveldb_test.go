@@ -0,0 +1,342 @@
+package goleveldb
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/syndtr/goleveldb/leveldb/filter"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"os"
+	"reflect"
+	"store"
+	"sync"
+	"testing"
+)
+
+var testConfigJson = []byte(`
+    {
+        "name" : "goleveldb",
+        "path" : "/tmp/test_goleveldb",
+        "compression":true,
+        "block_size" : 32768,
+        "write_buffer_size" : 2097152,
+        "cache_size" : 20971520
+    }
+    `)
+
+var testOnce sync.Once
+var testDB *store.DB
+
+func getTestDB() *store.DB {
+	f := func() {
+		var err error
+		os.RemoveAll("/tmp/test_goleveldb")
+
+		testDB, err = store.OpenWithJsonConfig(testConfigJson)
+		if err != nil {
+			println(err.Error())
+			panic(err)
+		}
+	}
+
+	testOnce.Do(f)
+	return testDB
+}
+
+func TestSimple(t *testing.T) {
+	db := getTestDB()
+
+	key := []byte("key")
+	value := []byte("hello world")
+	if err := db.Put(key, value); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := db.Get(key); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(v, value) {
+		t.Fatal("not equal")
+	}
+
+	if err := db.Delete(key); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := db.Get(key); err != nil {
+		t.Fatal(err)
+	} else if v != nil {
+		t.Fatal("must nil")
+	}
+}
+
+func TestBatch(t *testing.T) {
+	db := getTestDB()
+
+	key1 := []byte("key1")
+	key2 := []byte("key2")
+
+	value := []byte("hello world")
+
+	db.Put(key1, value)
+	db.Put(key2, value)
+
+	wb := db.NewWriteBatch()
+	defer wb.Close()
+
+	wb.Delete(key2)
+	wb.Put(key1, []byte("hello world2"))
+
+	if err := wb.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := db.Get(key2); err != nil {
+		t.Fatal(err)
+	} else if v != nil {
+		t.Fatal("must nil")
+	}
+
+	if v, err := db.Get(key1); err != nil {
+		t.Fatal(err)
+	} else if string(v) != "hello world2" {
+		t.Fatal(string(v))
+	}
+
+	wb.Delete(key1)
+
+	wb.Rollback()
+
+	if v, err := db.Get(key1); err != nil {
+		t.Fatal(err)
+	} else if string(v) != "hello world2" {
+		t.Fatal(string(v))
+	}
+
+	db.Delete(key1)
+}
+
+func checkIterator(it *store.RangeLimitIterator, cv ...int) error {
+	v := make([]string, 0, len(cv))
+	for ; it.Valid(); it.Next() {
+		k := it.Key()
+		v = append(v, string(k))
+	}
+
+	it.Close()
+
+	if len(v) != len(cv) {
+		return fmt.Errorf("len error %d != %d", len(v), len(cv))
+	}
+
+	for k, i := range cv {
+		if fmt.Sprintf("key_%d", i) != v[k] {
+			return fmt.Errorf("%s, %d", v[k], i)
+		}
+	}
+
+	return nil
+}
+
+func TestIterator(t *testing.T) {
+	db := getTestDB()
+
+	for i := 0; i < 10; i++ {
+		key := []byte(fmt.Sprintf("key_%d", i))
+		value := []byte("")
+		db.Put(key, value)
+	}
+
+	var it *store.RangeLimitIterator
+
+	k := func(i int) []byte {
+		return []byte(fmt.Sprintf("key_%d", i))
+	}
+
+	it = db.RangeLimitIterator(k(1), k(5), store.RangeClose, 0, -1)
+	if err := checkIterator(it, 1, 2, 3, 4, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	it = db.RangeLimitIterator(k(1), k(5), store.RangeClose, 1, 3)
+	if err := checkIterator(it, 2, 3, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	it = db.RangeLimitIterator(k(1), k(5), store.RangeLOpen, 0, -1)
+	if err := checkIterator(it, 2, 3, 4, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	it = db.RangeLimitIterator(k(1), k(5), store.RangeROpen, 0, -1)
+	if err := checkIterator(it, 1, 2, 3, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	it = db.RangeLimitIterator(k(1), k(5), store.RangeOpen, 0, -1)
+	if err := checkIterator(it, 2, 3, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	it = db.RevRangeLimitIterator(k(1), k(5), store.RangeClose, 0, -1)
+	if err := checkIterator(it, 5, 4, 3, 2, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	it = db.RevRangeLimitIterator(k(1), k(5), store.RangeClose, 1, 3)
+	if err := checkIterator(it, 4, 3, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	it = db.RevRangeLimitIterator(k(1), k(5), store.RangeLOpen, 0, -1)
+	if err := checkIterator(it, 5, 4, 3, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	it = db.RevRangeLimitIterator(k(1), k(5), store.RangeROpen, 0, -1)
+	if err := checkIterator(it, 4, 3, 2, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	it = db.RevRangeLimitIterator(k(1), k(5), store.RangeOpen, 0, -1)
+	if err := checkIterator(it, 4, 3, 2); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	db := getTestDB()
+
+	key := []byte("key")
+	value := []byte("hello world")
+
+	db.Put(key, value)
+
+	s, err := db.NewSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	db.Put(key, []byte("hello world2"))
+
+	if v, err := s.Get(key); err != nil {
+		t.Fatal(err)
+	} else if string(v) != string(value) {
+		t.Fatal(string(v))
+	}
+}
+
+func TestCloseMore(t *testing.T) {
+	cfg := new(store.Config)
+	cfg.Name = DriverName
+	cfg.Path = "/tmp/test_goleveldb_close"
+	cfg.CacheSize = 4 * 1024 * 1024
+	os.RemoveAll(cfg.Path)
+	for i := 0; i < 100; i++ {
+		db, err := store.Open(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		db.Put([]byte("key"), []byte("value"))
+
+		db.Close()
+	}
+}
+
+func TestCompactRange(t *testing.T) {
+	db := getTestDB()
+
+	for i := 0; i < 1000; i++ {
+		db.Put([]byte(fmt.Sprintf("compact_%d", i)), []byte("1"))
+	}
+
+	for i := 0; i < 1000; i += 2 {
+		db.Delete([]byte(fmt.Sprintf("compact_%d", i)))
+	}
+
+	if err := db.CompactRange([]byte("compact_"), []byte("compact_a")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.CompactRange(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := db.Get([]byte("compact_1")); err != nil {
+		t.Fatal(err)
+	} else if string(v) != "1" {
+		t.Fatal(string(v))
+	}
+
+	if v, err := db.Get([]byte("compact_0")); err != nil {
+		t.Fatal(err)
+	} else if v != nil {
+		t.Fatal("must nil")
+	}
+}
+
+func TestSizeOf(t *testing.T) {
+	db := getTestDB()
+
+	for i := 0; i < 1000; i++ {
+		db.Put([]byte(fmt.Sprintf("sizeof_%d", i)), bytes.Repeat([]byte("a"), 100))
+	}
+
+	//flush memtable so the data is counted
+	if err := db.CompactRange([]byte("sizeof_"), []byte("sizeof`")); err != nil {
+		t.Fatal(err)
+	}
+
+	sizes, err := db.SizeOf([]store.KeyRange{
+		{Start: []byte("sizeof_"), Limit: []byte("sizeof`")},
+		{Start: []byte("sizeof`"), Limit: []byte("sizeof~")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(sizes) != 2 {
+		t.Fatal(len(sizes))
+	} else if sizes[0] == 0 {
+		t.Fatal("must not 0")
+	} else if sizes[1] != 0 {
+		t.Fatal(sizes[1])
+	}
+
+	if v, err := db.GetProperty("leveldb.stats"); err != nil {
+		t.Fatal(err)
+	} else if len(v) == 0 {
+		t.Fatal("empty stats")
+	}
+
+	if _, err := db.GetProperty("leveldb.none"); err == nil {
+		t.Fatal("must error")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	cfg := new(store.Config)
+	cfg.Path = "/tmp/test_goleveldb_options"
+	cfg.BloomBits = 16
+	cfg.BlockRestartInterval = 8
+	cfg.ParanoidChecks = true
+	cfg.Sync = true
+
+	os.RemoveAll(cfg.Path)
+
+	opts := newOptions(cfg)
+	if !reflect.DeepEqual(opts.Filter, filter.NewBloomFilter(16)) {
+		t.Fatal("bloom bits not applied")
+	} else if opts.BlockRestartInterval != 8 {
+		t.Fatal(opts.BlockRestartInterval)
+	} else if opts.Strict != opt.StrictAll {
+		t.Fatal(opts.Strict)
+	}
+
+	idb, err := Store{}.Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idb.Close()
+
+	if db := idb.(*DB); !db.writeOpts.Sync {
+		t.Fatal("sync not applied")
+	}
+}
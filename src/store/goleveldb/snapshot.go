@@ -0,0 +1,31 @@
+package goleveldb
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+	"store"
+)
+
+type Snapshot struct {
+	db   *DB
+	snap *leveldb.Snapshot
+}
+
+func (s *Snapshot) Close() {
+	s.snap.Release()
+}
+
+func (s *Snapshot) Get(key []byte) ([]byte, error) {
+	v, err := s.snap.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	return v, err
+}
+
+func (s *Snapshot) NewIterator() store.IIterator {
+	it := &Iterator{
+		s.snap.NewIterator(nil, s.db.iteratorOpts),
+	}
+
+	return it
+}
@@ -0,0 +1,63 @@
+package memory
+
+type batchOp struct {
+	del   bool
+	key   []byte
+	value []byte
+}
+
+//dup copies b with the same len and cap, so append to it never changes the stored data.
+func dup(b []byte) []byte {
+	d := make([]byte, len(b))
+	copy(d, b)
+	return d
+}
+
+type WriteBatch struct {
+	db *DB
+
+	ops []batchOp
+}
+
+func (w *WriteBatch) Close() {
+	w.ops = nil
+}
+
+func (w *WriteBatch) Put(key, value []byte) {
+	w.ops = append(w.ops, batchOp{false, dup(key), dup(value)})
+}
+
+func (w *WriteBatch) Delete(key []byte) {
+	w.ops = append(w.ops, batchOp{true, dup(key), nil})
+}
+
+//all changes are visible at once after commit.
+func (w *WriteBatch) Commit() error {
+	w.db.Lock()
+	defer w.db.Unlock()
+
+	if w.db.closed {
+		return errClosed
+	}
+
+	root := w.db.root
+	for _, op := range w.ops {
+		if op.del {
+			root = remove(root, op.key)
+		} else {
+			root = put(root, op.key, op.value)
+		}
+	}
+	w.db.root = root
+
+	return nil
+}
+
+//memory store has nothing to sync.
+func (w *WriteBatch) SyncCommit() error {
+	return w.Commit()
+}
+
+func (w *WriteBatch) Rollback() {
+	w.ops = w.ops[0:0]
+}
@@ -0,0 +1,118 @@
+// Package memory is an in-memory store driver based on an ordered map,
+// all data are lost after closed, it can be used for tests and caches.
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"store"
+	"sync"
+)
+
+const DriverName = "memory"
+
+var errClosed = errors.New("memory store is closed")
+
+type Store struct {
+}
+
+func (s Store) String() string {
+	return DriverName
+}
+
+func (s Store) Open(cfg *store.Config) (store.IDB, error) {
+	db := new(DB)
+	return db, nil
+}
+
+func (s Store) Repair(cfg *store.Config) error {
+	return nil
+}
+
+type DB struct {
+	sync.RWMutex
+
+	root *node
+
+	closed bool
+}
+
+func (db *DB) getRoot() *node {
+	db.RLock()
+	root := db.root
+	db.RUnlock()
+
+	return root
+}
+
+func (db *DB) Close() error {
+	db.Lock()
+	db.root = nil
+	db.closed = true
+	db.Unlock()
+
+	return nil
+}
+
+func (db *DB) Get(key []byte) ([]byte, error) {
+	if n := get(db.getRoot(), key); n != nil {
+		return dup(n.value), nil
+	}
+
+	return nil, nil
+}
+
+func (db *DB) Put(key []byte, value []byte) error {
+	wb := db.NewWriteBatch()
+	wb.Put(key, value)
+	return wb.Commit()
+}
+
+func (db *DB) Delete(key []byte) error {
+	wb := db.NewWriteBatch()
+	wb.Delete(key)
+	return wb.Commit()
+}
+
+//memory store has nothing to compact.
+func (db *DB) CompactRange(start []byte, limit []byte) error {
+	return nil
+}
+
+//SizeOf returns the total size of the keys and values in each range.
+func (db *DB) SizeOf(ranges []store.KeyRange) ([]int64, error) {
+	root := db.getRoot()
+
+	sizes := make([]int64, len(ranges))
+	for i, r := range ranges {
+		for n := seek(root, r.Start); n != nil; n = next(root, n.key) {
+			if r.Limit != nil && bytes.Compare(n.key, r.Limit) >= 0 {
+				break
+			}
+			sizes[i] += int64(len(n.key) + len(n.value))
+		}
+	}
+	return sizes, nil
+}
+
+func (db *DB) GetProperty(name string) (string, error) {
+	return "", fmt.Errorf("memory: unknown property %s", name)
+}
+
+func (db *DB) NewWriteBatch() store.IWriteBatch {
+	return &WriteBatch{db: db}
+}
+
+func (db *DB) NewSnapshot() (store.ISnapshot, error) {
+	return &Snapshot{db.getRoot()}, nil
+}
+
+//the iterator sees the data when created, like leveldb.
+func (db *DB) NewIterator() store.IIterator {
+	return &Iterator{root: db.getRoot()}
+}
+
+func init() {
+	store.Register(Store{})
+}
@@ -0,0 +1,55 @@
+package memory
+
+type Iterator struct {
+	root *node
+
+	//the current node, nil if invalid
+	n *node
+}
+
+func (it *Iterator) Key() []byte {
+	if it.n == nil {
+		return nil
+	}
+	return it.n.key
+}
+
+func (it *Iterator) Value() []byte {
+	if it.n == nil {
+		return nil
+	}
+	return it.n.value
+}
+
+func (it *Iterator) Close() {
+	it.root = nil
+	it.n = nil
+}
+
+func (it *Iterator) Valid() bool {
+	return it.n != nil
+}
+
+func (it *Iterator) Next() {
+	if it.n != nil {
+		it.n = next(it.root, it.n.key)
+	}
+}
+
+func (it *Iterator) Prev() {
+	if it.n != nil {
+		it.n = prev(it.root, it.n.key)
+	}
+}
+
+func (it *Iterator) First() {
+	it.n = first(it.root)
+}
+
+func (it *Iterator) Last() {
+	it.n = last(it.root)
+}
+
+func (it *Iterator) Seek(key []byte) {
+	it.n = seek(it.root, key)
+}
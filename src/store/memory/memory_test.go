@@ -0,0 +1,302 @@
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"store"
+	"sync"
+	"testing"
+)
+
+var testConfigJson = []byte(`
+    {
+        "name" : "memory"
+    }
+    `)
+
+var testOnce sync.Once
+var testDB *store.DB
+
+func getTestDB() *store.DB {
+	f := func() {
+		var err error
+		testDB, err = store.OpenWithJsonConfig(testConfigJson)
+		if err != nil {
+			println(err.Error())
+			panic(err)
+		}
+	}
+
+	testOnce.Do(f)
+	return testDB
+}
+
+func TestSimple(t *testing.T) {
+	db := getTestDB()
+
+	key := []byte("key")
+	value := []byte("hello world")
+	if err := db.Put(key, value); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := db.Get(key); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(v, value) {
+		t.Fatal("not equal")
+	}
+
+	if err := db.Delete(key); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := db.Get(key); err != nil {
+		t.Fatal(err)
+	} else if v != nil {
+		t.Fatal("must nil")
+	}
+}
+
+func TestBatch(t *testing.T) {
+	db := getTestDB()
+
+	key1 := []byte("key1")
+	key2 := []byte("key2")
+
+	value := []byte("hello world")
+
+	db.Put(key1, value)
+	db.Put(key2, value)
+
+	wb := db.NewWriteBatch()
+	defer wb.Close()
+
+	wb.Delete(key2)
+	wb.Put(key1, []byte("hello world2"))
+
+	if err := wb.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := db.Get(key2); err != nil {
+		t.Fatal(err)
+	} else if v != nil {
+		t.Fatal("must nil")
+	}
+
+	if v, err := db.Get(key1); err != nil {
+		t.Fatal(err)
+	} else if string(v) != "hello world2" {
+		t.Fatal(string(v))
+	}
+
+	wb.Delete(key1)
+
+	wb.Rollback()
+
+	if v, err := db.Get(key1); err != nil {
+		t.Fatal(err)
+	} else if string(v) != "hello world2" {
+		t.Fatal(string(v))
+	}
+
+	db.Delete(key1)
+}
+
+func checkIterator(it *store.RangeLimitIterator, cv ...int) error {
+	v := make([]string, 0, len(cv))
+	for ; it.Valid(); it.Next() {
+		k := it.Key()
+		v = append(v, string(k))
+	}
+
+	it.Close()
+
+	if len(v) != len(cv) {
+		return fmt.Errorf("len error %d != %d", len(v), len(cv))
+	}
+
+	for k, i := range cv {
+		if fmt.Sprintf("key_%d", i) != v[k] {
+			return fmt.Errorf("%s, %d", v[k], i)
+		}
+	}
+
+	return nil
+}
+
+func TestIterator(t *testing.T) {
+	db := getTestDB()
+
+	for i := 0; i < 10; i++ {
+		key := []byte(fmt.Sprintf("key_%d", i))
+		value := []byte("")
+		db.Put(key, value)
+	}
+
+	var it *store.RangeLimitIterator
+
+	k := func(i int) []byte {
+		return []byte(fmt.Sprintf("key_%d", i))
+	}
+
+	it = db.RangeLimitIterator(k(1), k(5), store.RangeClose, 0, -1)
+	if err := checkIterator(it, 1, 2, 3, 4, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	it = db.RangeLimitIterator(k(1), k(5), store.RangeClose, 1, 3)
+	if err := checkIterator(it, 2, 3, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	it = db.RangeLimitIterator(k(1), k(5), store.RangeLOpen, 0, -1)
+	if err := checkIterator(it, 2, 3, 4, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	it = db.RangeLimitIterator(k(1), k(5), store.RangeROpen, 0, -1)
+	if err := checkIterator(it, 1, 2, 3, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	it = db.RangeLimitIterator(k(1), k(5), store.RangeOpen, 0, -1)
+	if err := checkIterator(it, 2, 3, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	it = db.RevRangeLimitIterator(k(1), k(5), store.RangeClose, 0, -1)
+	if err := checkIterator(it, 5, 4, 3, 2, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	it = db.RevRangeLimitIterator(k(1), k(5), store.RangeClose, 1, 3)
+	if err := checkIterator(it, 4, 3, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	it = db.RevRangeLimitIterator(k(1), k(5), store.RangeLOpen, 0, -1)
+	if err := checkIterator(it, 5, 4, 3, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	it = db.RevRangeLimitIterator(k(1), k(5), store.RangeROpen, 0, -1)
+	if err := checkIterator(it, 4, 3, 2, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	it = db.RevRangeLimitIterator(k(1), k(5), store.RangeOpen, 0, -1)
+	if err := checkIterator(it, 4, 3, 2); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	db := getTestDB()
+
+	key := []byte("key")
+	value := []byte("hello world")
+
+	db.Put(key, value)
+
+	s, err := db.NewSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	db.Put(key, []byte("hello world2"))
+
+	if v, err := s.Get(key); err != nil {
+		t.Fatal(err)
+	} else if string(v) != string(value) {
+		t.Fatal(string(v))
+	}
+}
+
+func TestIteratorSnapshot(t *testing.T) {
+	db := getTestDB()
+
+	key := []byte("iter_key")
+	db.Put(key, []byte("1"))
+
+	it := db.NewIterator()
+	defer it.Close()
+
+	db.Put(key, []byte("2"))
+	db.Put([]byte("iter_key_new"), []byte("1"))
+
+	it.Seek(key)
+	if !it.Valid() {
+		t.Fatal("must valid")
+	} else if string(it.Value()) != "1" {
+		t.Fatal(string(it.Value()))
+	}
+
+	it.Next()
+	if it.Valid() && string(it.Key()) == "iter_key_new" {
+		t.Fatal("must not see the key put after created")
+	}
+}
+
+func TestBatchAtomic(t *testing.T) {
+	db := getTestDB()
+
+	wb := db.NewWriteBatch()
+	defer wb.Close()
+
+	s, err := db.NewSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 100; i++ {
+		wb.Put([]byte(fmt.Sprintf("batch_%d", i)), []byte("1"))
+	}
+
+	if v, err := db.Get([]byte("batch_0")); err != nil {
+		t.Fatal(err)
+	} else if v != nil {
+		t.Fatal("must nil before commit")
+	}
+
+	if err := wb.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	it := db.RangeLimitIterator([]byte("batch_"), []byte("batch_a"), store.RangeClose, 0, -1)
+	n := 0
+	for ; it.Valid(); it.Next() {
+		n++
+	}
+	it.Close()
+
+	if n != 100 {
+		t.Fatal(n)
+	}
+
+	if v, err := s.Get([]byte("batch_0")); err != nil {
+		t.Fatal(err)
+	} else if v != nil {
+		t.Fatal("snapshot must not see the batch")
+	}
+}
+
+func TestSizeOf(t *testing.T) {
+	db := getTestDB()
+
+	db.Put([]byte("sizeof_1"), []byte("12"))
+	db.Put([]byte("sizeof_2"), []byte("1234"))
+
+	sizes, err := db.SizeOf([]store.KeyRange{
+		{Start: []byte("sizeof_"), Limit: []byte("sizeof_2")},
+		{Start: []byte("sizeof_"), Limit: []byte("sizeof`")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if sizes[0] != 10 {
+		t.Fatal(sizes[0])
+	} else if sizes[1] != 22 {
+		t.Fatal(sizes[1])
+	}
+}
@@ -0,0 +1,25 @@
+package memory
+
+import (
+	"store"
+)
+
+type Snapshot struct {
+	root *node
+}
+
+func (s *Snapshot) Close() {
+	s.root = nil
+}
+
+func (s *Snapshot) Get(key []byte) ([]byte, error) {
+	if n := get(s.root, key); n != nil {
+		return dup(n.value), nil
+	}
+
+	return nil, nil
+}
+
+func (s *Snapshot) NewIterator() store.IIterator {
+	return &Iterator{root: s.root}
+}
@@ -0,0 +1,162 @@
+package memory
+
+import (
+	"bytes"
+	"math/rand"
+)
+
+//node is a node of the persistent treap, it is never changed after created,
+//so a root can be shared by snapshots and iterators safely.
+type node struct {
+	key   []byte
+	value []byte
+
+	priority int32
+
+	left  *node
+	right *node
+}
+
+func (n *node) clone() *node {
+	c := *n
+	return &c
+}
+
+func get(n *node, key []byte) *node {
+	for n != nil {
+		c := bytes.Compare(key, n.key)
+		if c == 0 {
+			return n
+		} else if c < 0 {
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+	return nil
+}
+
+//split splits the tree into the keys less than key and the keys greater than key,
+//the node with key is returned too if exists.
+func split(n *node, key []byte) (left *node, mid *node, right *node) {
+	if n == nil {
+		return nil, nil, nil
+	}
+
+	c := bytes.Compare(key, n.key)
+	if c == 0 {
+		return n.left, n, n.right
+	} else if c < 0 {
+		l, m, r := split(n.left, key)
+		n = n.clone()
+		n.left = r
+		return l, m, n
+	} else {
+		l, m, r := split(n.right, key)
+		n = n.clone()
+		n.right = l
+		return n, m, r
+	}
+}
+
+//merge merges two trees, all keys in left must be less than the keys in right.
+func merge(left *node, right *node) *node {
+	if left == nil {
+		return right
+	} else if right == nil {
+		return left
+	}
+
+	if left.priority > right.priority {
+		left = left.clone()
+		left.right = merge(left.right, right)
+		return left
+	} else {
+		right = right.clone()
+		right.left = merge(left, right.left)
+		return right
+	}
+}
+
+func put(n *node, key []byte, value []byte) *node {
+	l, _, r := split(n, key)
+
+	m := &node{
+		key:      key,
+		value:    value,
+		priority: rand.Int31(),
+	}
+
+	return merge(merge(l, m), r)
+}
+
+func remove(n *node, key []byte) *node {
+	if get(n, key) == nil {
+		return n
+	}
+
+	l, _, r := split(n, key)
+	return merge(l, r)
+}
+
+//seek returns the first node whose key is greater than or equal to key.
+func seek(n *node, key []byte) *node {
+	var found *node
+	for n != nil {
+		if bytes.Compare(n.key, key) >= 0 {
+			found = n
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+	return found
+}
+
+//next returns the first node whose key is greater than key.
+func next(n *node, key []byte) *node {
+	var found *node
+	for n != nil {
+		if bytes.Compare(n.key, key) > 0 {
+			found = n
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+	return found
+}
+
+//prev returns the last node whose key is less than key.
+func prev(n *node, key []byte) *node {
+	var found *node
+	for n != nil {
+		if bytes.Compare(n.key, key) < 0 {
+			found = n
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	return found
+}
+
+func first(n *node) *node {
+	if n == nil {
+		return nil
+	}
+	for n.left != nil {
+		n = n.left
+	}
+	return n
+}
+
+func last(n *node) *node {
+	if n == nil {
+		return nil
+	}
+	for n.right != nil {
+		n = n.right
+	}
+	return n
+}
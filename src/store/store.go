@@ -0,0 +1,119 @@
+// Package store defines the storage driver interface used by ledis,
+// the drivers are registered by importing them, like database/sql.
+//
+//     import _ "store/goleveldb"
+//
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const DefaultDriverName = "goleveldb"
+
+type Config struct {
+	//the registered driver name, use DefaultDriverName if empty
+	Name string `json:"name"`
+
+	Path string `json:"path"`
+
+	Compression     bool `json:"compression"`
+	BlockSize       int  `json:"block_size"`
+	WriteBufferSize int  `json:"write_buffer_size"`
+	CacheSize       int  `json:"cache_size"`
+	MaxOpenFiles    int  `json:"max_open_files"`
+
+	//bloom filter bits per key, default 10
+	BloomBits int `json:"bloom_bits"`
+
+	//number of keys between restart points for delta encoding of keys, default 16
+	BlockRestartInterval int `json:"block_restart_interval"`
+
+	//check data aggressively, stop on any corruption
+	ParanoidChecks bool `json:"paranoid_checks"`
+
+	//sync every write to disk, safe but slow
+	Sync bool `json:"sync"`
+}
+
+var driversLock sync.RWMutex
+var drivers = map[string]Driver{}
+
+func Register(d Driver) {
+	driversLock.Lock()
+	defer driversLock.Unlock()
+
+	if _, ok := drivers[d.String()]; ok {
+		panic(fmt.Sprintf("store %s has been registered", d.String()))
+	}
+
+	drivers[d.String()] = d
+}
+
+//Drivers returns the sorted names of the registered drivers.
+func Drivers() []string {
+	driversLock.RLock()
+	defer driversLock.RUnlock()
+
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func getDriver(name string) (Driver, error) {
+	if len(name) == 0 {
+		name = DefaultDriverName
+	}
+
+	driversLock.RLock()
+	d, ok := drivers[name]
+	driversLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("store %s is not registered", name)
+	}
+	return d, nil
+}
+
+func OpenWithJsonConfig(configJson json.RawMessage) (*DB, error) {
+	cfg := new(Config)
+	err := json.Unmarshal(configJson, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return Open(cfg)
+}
+
+func Open(cfg *Config) (*DB, error) {
+	d, err := getDriver(cfg.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	idb, err := d.Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db := new(DB)
+	db.cfg = cfg
+	db.db = idb
+
+	return db, nil
+}
+
+func Repair(cfg *Config) error {
+	d, err := getDriver(cfg.Name)
+	if err != nil {
+		return err
+	}
+
+	return d.Repair(cfg)
+}
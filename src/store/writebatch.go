@@ -0,0 +1,29 @@
+package store
+
+type WriteBatch struct {
+	wb IWriteBatch
+}
+
+func (w *WriteBatch) Close() {
+	w.wb.Close()
+}
+
+func (w *WriteBatch) Put(key []byte, value []byte) {
+	w.wb.Put(key, value)
+}
+
+func (w *WriteBatch) Delete(key []byte) {
+	w.wb.Delete(key)
+}
+
+func (w *WriteBatch) Commit() error {
+	return w.wb.Commit()
+}
+
+func (w *WriteBatch) SyncCommit() error {
+	return w.wb.SyncCommit()
+}
+
+func (w *WriteBatch) Rollback() {
+	w.wb.Rollback()
+}